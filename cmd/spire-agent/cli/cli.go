@@ -19,6 +19,9 @@ func Run(args []string) int {
 		"plugin-info": func() (cli.Command, error) {
 			return &command.PluginInfoCommand{}, nil
 		},
+		"fetch bundle": func() (cli.Command, error) {
+			return &command.FetchBundleCommand{}, nil
+		},
 	}
 
 	exitStatus, err := c.Run()
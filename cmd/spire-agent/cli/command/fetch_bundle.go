@@ -0,0 +1,152 @@
+package command
+
+import (
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/spiffe/spire/pkg/common/bundle"
+	"github.com/spiffe/spire/pkg/common/output"
+	"github.com/spiffe/spire/pkg/common/profile"
+	"github.com/spiffe/spire/proto/api/workload"
+)
+
+// FetchBundleCommand writes the CA bundle of the agent's trust domain to
+// stdout, as PEM or as a JWK set. The bundle is fetched through the Workload
+// API, so the caller must be entitled to at least one registration entry.
+// Errors are written to stderr, so that they never end up in a redirected
+// trust store file.
+type FetchBundleCommand struct {
+	Client workload.WorkloadClient
+	Out    io.Writer
+	Err    io.Writer
+}
+
+const (
+	pemFormat  = "pem"
+	jwksFormat = "jwks"
+)
+
+// bundleOutput is the JSON form of a fetched bundle. It holds either the
+// PEM encoded certificates or the keys, depending on the bundle format.
+type bundleOutput struct {
+	TrustDomain  string       `json:"trust_domain"`
+	Certificates []string     `json:"certificates,omitempty"`
+	Keys         []bundle.JWK `json:"keys,omitempty"`
+}
+
+func (*FetchBundleCommand) Help() string {
+	return "Usage: spire-agent fetch bundle [-socketPath <path>] [-format <pem|jwks>] [-output <text|json>] [-profile <name>]"
+}
+
+func (c *FetchBundleCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("fetch bundle", flag.ContinueOnError)
+	socketPath := flags.String("socketPath", defaultSocketPath, "Location of the workload API socket")
+	bundleFormat := flags.String("format", pemFormat, "Bundle format, pem or jwks")
+	format := output.AddFlag(flags)
+	profileName := profile.AddFlag(flags)
+
+	if c.Err == nil {
+		c.Err = os.Stderr
+	}
+	flags.SetOutput(c.Err)
+
+	err := flags.Parse(args)
+	if err != nil {
+		return -1
+	}
+
+	err = profile.Apply(flags, profile.DefaultPath(), *profileName)
+	if err != nil {
+		fmt.Fprintln(c.Err, err.Error())
+		return -1
+	}
+
+	p, err := output.NewPrinter(*format, c.Out, c.Err)
+	if err != nil {
+		p.PrintError(err)
+		return -1
+	}
+
+	if *bundleFormat != pemFormat && *bundleFormat != jwksFormat {
+		p.PrintError(fmt.Errorf("Unknown bundle format: %s", *bundleFormat))
+		return -1
+	}
+
+	if c.Client == nil {
+		conn, err := dialWorkloadAPI(*socketPath)
+		if err != nil {
+			p.PrintError(err)
+			return -1
+		}
+		defer conn.Close()
+
+		c.Client = workload.NewWorkloadClient(conn)
+	}
+
+	b, err := bundle.Fetch(context.Background(), c.Client)
+	if err != nil {
+		p.PrintError(err)
+		return -1
+	}
+
+	if *bundleFormat == jwksFormat {
+		err = c.printJWKS(p, b)
+	} else {
+		err = c.printPEM(p, b)
+	}
+	if err != nil {
+		p.PrintError(err)
+		return -1
+	}
+
+	return 0
+}
+
+func (*FetchBundleCommand) Synopsis() string {
+	return "Fetches the CA bundle of the agent's trust domain from the workload API"
+}
+
+func (*FetchBundleCommand) printPEM(p *output.Printer, b *bundle.Bundle) error {
+	if !p.IsJSON() {
+		_, err := p.Out().Write(b.PEM())
+		return err
+	}
+
+	out := bundleOutput{TrustDomain: b.TrustDomain}
+	for _, cert := range b.Certificates {
+		block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+		out.Certificates = append(out.Certificates, string(block))
+	}
+
+	return p.PrintJSON(out)
+}
+
+func (*FetchBundleCommand) printJWKS(p *output.Printer, b *bundle.Bundle) error {
+	jwks, err := b.JWKS()
+	if err != nil {
+		return err
+	}
+
+	// The text form of a JWK set is the JWK set document itself
+	if !p.IsJSON() {
+		return p.PrintJSON(jwks)
+	}
+
+	return p.PrintJSON(bundleOutput{TrustDomain: b.TrustDomain, Keys: jwks.Keys})
+}
+
+func dialWorkloadAPI(socketPath string) (*grpc.ClientConn, error) {
+	dialer := func(addr string, timeout time.Duration) (net.Conn, error) {
+		return net.DialTimeout("unix", addr, timeout)
+	}
+
+	return grpc.Dial(socketPath, grpc.WithInsecure(), grpc.WithDialer(dialer))
+}
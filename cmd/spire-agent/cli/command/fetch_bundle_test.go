@@ -0,0 +1,209 @@
+package command
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/spiffe/spire/pkg/common/bundle"
+	"github.com/spiffe/spire/proto/api/workload"
+)
+
+type fakeWorkloadClient struct {
+	bundles *workload.Bundles
+}
+
+func (c *fakeWorkloadClient) FetchBundles(ctx context.Context, in *workload.SpiffeID, opts ...grpc.CallOption) (*workload.Bundles, error) {
+	return c.bundles, nil
+}
+
+func (c *fakeWorkloadClient) FetchAllBundles(ctx context.Context, in *workload.Empty, opts ...grpc.CallOption) (*workload.Bundles, error) {
+	return c.bundles, nil
+}
+
+func TestFetchBundleCommand(t *testing.T) {
+	ownCert := createCertificate(t)
+
+	client := &fakeWorkloadClient{
+		bundles: &workload.Bundles{
+			Bundles: []*workload.WorkloadEntry{
+				{SpiffeId: "spiffe://example.org/foo", SvidBundle: ownCert},
+			},
+		},
+	}
+
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := &FetchBundleCommand{Client: client, Out: out, Err: errOut}
+	require.Equal(t, 0, cmd.Run([]string{}))
+	assert.Equal(t, ownCert, pemBytes(t, out.Bytes()))
+	assert.Empty(t, errOut.Bytes())
+}
+
+func TestFetchBundleCommand_NoEntries(t *testing.T) {
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	client := &fakeWorkloadClient{bundles: &workload.Bundles{}}
+	cmd := &FetchBundleCommand{Client: client, Out: out, Err: errOut}
+	assert.Equal(t, -1, cmd.Run([]string{}))
+	assert.Empty(t, out.Bytes())
+	assert.Contains(t, errOut.String(), "No registration entries found")
+}
+
+func TestFetchBundleCommand_EmptyBundle(t *testing.T) {
+	client := &fakeWorkloadClient{
+		bundles: &workload.Bundles{
+			Bundles: []*workload.WorkloadEntry{
+				{
+					SpiffeId:         "spiffe://example.org/foo",
+					FederatedBundles: map[string][]byte{"spiffe://otherdomain.test": createCertificate(t)},
+				},
+			},
+		},
+	}
+
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := &FetchBundleCommand{Client: client, Out: out, Err: errOut}
+	assert.Equal(t, -1, cmd.Run([]string{}))
+	assert.Empty(t, out.Bytes())
+	assert.Contains(t, errOut.String(), "The agent has no bundle for its own trust domain")
+}
+
+func TestFetchBundleCommand_JSON(t *testing.T) {
+	ownCert := createCertificate(t)
+
+	client := &fakeWorkloadClient{
+		bundles: &workload.Bundles{
+			Bundles: []*workload.WorkloadEntry{
+				{SpiffeId: "spiffe://example.org/foo", SvidBundle: ownCert},
+			},
+		},
+	}
+
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := &FetchBundleCommand{Client: client, Out: out, Err: errOut}
+	require.Equal(t, 0, cmd.Run([]string{"-output", "json"}))
+
+	var result bundleOutput
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.Equal(t, "example.org", result.TrustDomain)
+	require.Len(t, result.Certificates, 1)
+	assert.Equal(t, ownCert, pemBytes(t, []byte(result.Certificates[0])))
+	assert.Empty(t, result.Keys)
+
+	// Errors are JSON objects too
+	out.Reset()
+	cmd.Client = &fakeWorkloadClient{bundles: &workload.Bundles{}}
+	assert.Equal(t, -1, cmd.Run([]string{"-output", "json"}))
+	assert.Empty(t, out.Bytes())
+	assert.JSONEq(t, `{"error": "No registration entries found for the caller"}`, errOut.String())
+
+	errOut.Reset()
+	assert.Equal(t, -1, cmd.Run([]string{"-output", "xml"}))
+	assert.Equal(t, "Unknown output format: xml\n", errOut.String())
+}
+
+func TestFetchBundleCommand_JWKS(t *testing.T) {
+	ownCert := createCertificate(t)
+
+	client := &fakeWorkloadClient{
+		bundles: &workload.Bundles{
+			Bundles: []*workload.WorkloadEntry{
+				{SpiffeId: "spiffe://example.org/foo", SvidBundle: ownCert},
+			},
+		},
+	}
+
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := &FetchBundleCommand{Client: client, Out: out, Err: errOut}
+	require.Equal(t, 0, cmd.Run([]string{"-format", "jwks"}))
+
+	// The text output is a plain JWK set
+	var jwks bundle.JWKS
+	require.NoError(t, json.Unmarshal(out.Bytes(), &jwks))
+	require.Len(t, jwks.Keys, 1)
+	assert.Equal(t, "EC", jwks.Keys[0].KeyType)
+	assert.Equal(t, []string{base64.StdEncoding.EncodeToString(ownCert)}, jwks.Keys[0].X5C)
+
+	out.Reset()
+	require.Equal(t, 0, cmd.Run([]string{"-format", "jwks", "-output", "json"}))
+
+	var result bundleOutput
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.Equal(t, "example.org", result.TrustDomain)
+	assert.Equal(t, jwks.Keys, result.Keys)
+	assert.Empty(t, result.Certificates)
+
+	assert.Equal(t, -1, cmd.Run([]string{"-format", "der"}))
+	assert.Equal(t, "Unknown bundle format: der\n", errOut.String())
+}
+
+func TestFetchBundleCommand_Profile(t *testing.T) {
+	home, err := ioutil.TempDir("", "spire-fetch-bundle-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(home)
+
+	require.NoError(t, os.Mkdir(path.Join(home, ".spire"), 0700))
+	config := `profile "scripts" { output = "json" }`
+	require.NoError(t, ioutil.WriteFile(path.Join(home, ".spire", "cli"), []byte(config), 0600))
+
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", home)
+
+	client := &fakeWorkloadClient{
+		bundles: &workload.Bundles{
+			Bundles: []*workload.WorkloadEntry{
+				{SpiffeId: "spiffe://example.org/foo", SvidBundle: createCertificate(t)},
+			},
+		},
+	}
+
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := &FetchBundleCommand{Client: client, Out: out, Err: errOut}
+	require.Equal(t, 0, cmd.Run([]string{"-profile", "scripts"}))
+
+	var result bundleOutput
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.Equal(t, "example.org", result.TrustDomain)
+
+	assert.Equal(t, -1, cmd.Run([]string{"-profile", "unknown"}))
+	assert.Contains(t, errOut.String(), "Profile unknown not found")
+}
+
+func createCertificate(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return cert
+}
+
+func pemBytes(t *testing.T, data []byte) []byte {
+	block, rest := pem.Decode(data)
+	require.NotNil(t, block)
+	assert.Empty(t, rest)
+	return block.Bytes
+}
@@ -0,0 +1,63 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeServerClient struct {
+	spi.ServerClient
+
+	reply *spi.PluginInfoReply
+	err   error
+}
+
+func (c *fakeServerClient) PluginInfo(ctx context.Context, in *spi.PluginInfoRequest, opts ...grpc.CallOption) (*spi.PluginInfoReply, error) {
+	return c.reply, c.err
+}
+
+func TestPluginInfoCommand_Help(t *testing.T) {
+	cmd := &PluginInfoCommand{}
+	assert.True(t, strings.HasPrefix(cmd.Help(), "Usage: spire-agent plugin-info"))
+	assert.Contains(t, cmd.Synopsis(), "spire-agent")
+}
+
+func TestPluginInfoCommand_Text(t *testing.T) {
+	reply := &spi.PluginInfoReply{
+		PluginInfo: []*spi.GetPluginInfoResponse{
+			{Name: "foo", Category: "bar", Version: "1.0"},
+		},
+	}
+
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := &PluginInfoCommand{
+		Client: &fakeServerClient{reply: reply},
+		Out:    out,
+		Err:    errOut,
+	}
+	require.Equal(t, 0, cmd.Run(nil))
+	assert.Empty(t, errOut.String())
+	assert.True(t, strings.HasPrefix(out.String(), "PluginClient information: "))
+	assert.Contains(t, out.String(), `"foo"`)
+}
+
+func TestPluginInfoCommand_TextError(t *testing.T) {
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := &PluginInfoCommand{
+		Client: &fakeServerClient{err: errors.New("unavailable")},
+		Out:    out,
+		Err:    errOut,
+	}
+	assert.Equal(t, -1, cmd.Run(nil))
+	assert.Empty(t, out.String())
+	assert.Equal(t, "error: unavailable\n", errOut.String())
+}
@@ -26,7 +26,10 @@ const (
 	// TODO: Make my defaults sane
 	defaultDataDir   = "."
 	defaultLogLevel  = "INFO"
+	defaultLogFormat = "text"
 	defaultPluginDir = "conf/plugin/agent"
+
+	defaultRotationFraction = 0.5
 )
 
 // Struct representing available configurables for file and CLI
@@ -42,6 +45,9 @@ type CmdConfig struct {
 	PluginDir  string
 	LogFile    string
 	LogLevel   string
+	LogFormat  string
+
+	RotationFraction float64
 }
 
 type RunCommand struct {
@@ -118,6 +124,8 @@ func setOptsFromCLI(c *agent.Config, args []string) error {
 	flags.StringVar(&cmdConfig.PluginDir, "pluginDir", "", "Plugin conf.d configuration directory")
 	flags.StringVar(&cmdConfig.LogFile, "logFile", "", "File to write logs to")
 	flags.StringVar(&cmdConfig.LogLevel, "logLevel", "", "DEBUG, INFO, WARN or ERROR")
+	flags.StringVar(&cmdConfig.LogFormat, "logFormat", "", "text or json")
+	flags.Float64Var(&cmdConfig.RotationFraction, "rotationFraction", 0, "Fraction of an SVID's remaining lifetime after which workloads should rotate it")
 
 	err := flags.Parse(args)
 	if err != nil {
@@ -178,16 +186,25 @@ func mergeAgentConfig(orig *agent.Config, cmd *CmdConfig) error {
 		orig.PluginDir = cmd.PluginDir
 	}
 
+	if cmd.RotationFraction != 0 {
+		orig.RotationFraction = cmd.RotationFraction
+	}
+
 	// Handle log file and level
-	if cmd.LogFile != "" || cmd.LogLevel != "" {
+	if cmd.LogFile != "" || cmd.LogLevel != "" || cmd.LogFormat != "" {
 		logLevel := defaultLogLevel
 		if cmd.LogLevel != "" {
 			logLevel = cmd.LogLevel
 		}
 
-		logger, err := log.NewLogger(logLevel, cmd.LogFile)
+		logFormat := defaultLogFormat
+		if cmd.LogFormat != "" {
+			logFormat = cmd.LogFormat
+		}
+
+		logger, err := log.NewLogger(logLevel, logFormat, cmd.LogFile)
 		if err != nil {
-			return fmt.Errorf("Could not open log file %s: %s", cmd.LogFile, err)
+			return err
 		}
 
 		orig.Log = logger
@@ -209,6 +226,10 @@ func validateConfig(c *agent.Config) error {
 		return errors.New("TrustBundle is required")
 	}
 
+	if c.RotationFraction <= 0 || c.RotationFraction >= 1 {
+		return errors.New("RotationFraction must be between 0 and 1")
+	}
+
 	return nil
 }
 
@@ -223,7 +244,7 @@ func newDefaultConfig() *agent.Config {
 	shutdownCh := make(chan struct{})
 
 	// log.NewLogger() cannot return error when using STDOUT
-	logger, _ := log.NewLogger(defaultLogLevel, "")
+	logger, _ := log.NewLogger(defaultLogLevel, defaultLogFormat, "")
 	serverAddress := &net.TCPAddr{}
 
 	return &agent.Config{
@@ -235,6 +256,8 @@ func newDefaultConfig() *agent.Config {
 		ShutdownCh:    shutdownCh,
 		Log:           logger,
 		ServerAddress: serverAddress,
+
+		RotationFraction: defaultRotationFraction,
 	}
 }
 
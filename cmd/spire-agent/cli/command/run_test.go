@@ -0,0 +1,37 @@
+package command
+
+import (
+	"crypto/x509"
+	"net"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfig_RotationFraction(t *testing.T) {
+	config := newDefaultConfig()
+	config.ServerAddress = &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 8081}
+	config.TrustDomain = url.URL{Scheme: "spiffe", Host: "example.org"}
+	config.TrustBundle = x509.NewCertPool()
+	assert.NoError(t, validateConfig(config))
+
+	for _, fraction := range []float64{0.1, 0.9} {
+		config.RotationFraction = fraction
+		assert.NoError(t, validateConfig(config), "fraction %v", fraction)
+	}
+
+	for _, fraction := range []float64{-0.5, 0, 1, 1.5} {
+		config.RotationFraction = fraction
+		assert.Error(t, validateConfig(config), "fraction %v", fraction)
+	}
+}
+
+func TestSetOptsFromCLI_RotationFraction(t *testing.T) {
+	config := newDefaultConfig()
+	assert.Equal(t, defaultRotationFraction, config.RotationFraction)
+
+	err := setOptsFromCLI(config, []string{"-rotationFraction", "0.8"})
+	assert.NoError(t, err)
+	assert.Equal(t, 0.8, config.RotationFraction)
+}
@@ -1,40 +1,89 @@
 package command
 
 import (
-	"log"
+	"flag"
+	"fmt"
+	"io"
+	"os"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 
+	"github.com/spiffe/spire/pkg/common/output"
+	"github.com/spiffe/spire/pkg/common/profile"
 	spi "github.com/spiffe/spire/proto/common/plugin"
 )
 
 type PluginInfoCommand struct {
+	Client spi.ServerClient
+
+	// Out and Err default to stdout and stderr
+	Out io.Writer
+	Err io.Writer
 }
 
 func (*PluginInfoCommand) Help() string {
-	return "Usage: spire-server plugin-info"
+	return "Usage: spire-server plugin-info [-output <text|json>] [-profile <name>]"
 }
 
-func (*PluginInfoCommand) Run(args []string) int {
+func (c *PluginInfoCommand) Run(args []string) int {
 	const (
 		address = "localhost:8081" //TODO: read this from the cli arguments @kunzimariano
 	)
-	conn, err := grpc.Dial(address, grpc.WithInsecure())
+
+	flags := flag.NewFlagSet("plugin-info", flag.ContinueOnError)
+	format := output.AddFlag(flags)
+	profileName := profile.AddFlag(flags)
+
+	if c.Err == nil {
+		c.Err = os.Stderr
+	}
+	flags.SetOutput(c.Err)
+
+	err := flags.Parse(args)
+	if err != nil {
+		return -1
+	}
+
+	err = profile.Apply(flags, profile.DefaultPath(), *profileName)
 	if err != nil {
-		log.Fatalf("Could not connect to: %v", err)
+		fmt.Fprintln(c.Err, err.Error())
 		return -1
 	}
-	defer conn.Close()
-	c := spi.NewServerClient(conn)
 
-	r, err := c.PluginInfo(context.Background(), &spi.PluginInfoRequest{})
+	p, err := output.NewPrinter(*format, c.Out, c.Err)
+	if err != nil {
+		p.PrintError(err)
+		return -1
+	}
+
+	if c.Client == nil {
+		conn, err := grpc.Dial(address, grpc.WithInsecure())
+		if err != nil {
+			p.PrintError(fmt.Errorf("Could not connect to: %v", err))
+			return -1
+		}
+		defer conn.Close()
+		c.Client = spi.NewServerClient(conn)
+	}
+
+	r, err := c.Client.PluginInfo(context.Background(), &spi.PluginInfoRequest{})
 
 	if err != nil {
-		log.Fatalf("error: %v", err)
+		p.PrintError(fmt.Errorf("error: %v", err))
 		return -1
 	}
-	log.Printf("PluginClient information: %s", r)
+
+	if p.IsJSON() {
+		err = p.PrintJSON(r)
+		if err != nil {
+			p.PrintError(err)
+			return -1
+		}
+		return 0
+	}
+
+	fmt.Fprintf(p.Out(), "PluginClient information: %s\n", r)
 
 	return 0
 }
@@ -0,0 +1,66 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	spi "github.com/spiffe/spire/proto/common/plugin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeServerClient struct {
+	spi.ServerClient
+
+	reply *spi.PluginInfoReply
+	err   error
+}
+
+func (c *fakeServerClient) PluginInfo(ctx context.Context, in *spi.PluginInfoRequest, opts ...grpc.CallOption) (*spi.PluginInfoReply, error) {
+	return c.reply, c.err
+}
+
+func TestPluginInfoCommand_Help(t *testing.T) {
+	cmd := &PluginInfoCommand{}
+	assert.True(t, strings.HasPrefix(cmd.Help(), "Usage: spire-server plugin-info"))
+	assert.Contains(t, cmd.Synopsis(), "spire-server")
+}
+
+func TestPluginInfoCommand_JSON(t *testing.T) {
+	reply := &spi.PluginInfoReply{
+		PluginInfo: []*spi.GetPluginInfoResponse{
+			{Name: "foo", Category: "bar", Version: "1.0"},
+		},
+	}
+
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := &PluginInfoCommand{
+		Client: &fakeServerClient{reply: reply},
+		Out:    out,
+		Err:    errOut,
+	}
+	require.Equal(t, 0, cmd.Run([]string{"-output", "json"}))
+	assert.Empty(t, errOut.String())
+
+	result := new(spi.PluginInfoReply)
+	require.NoError(t, json.Unmarshal(out.Bytes(), result))
+	assert.Equal(t, reply, result)
+}
+
+func TestPluginInfoCommand_JSONError(t *testing.T) {
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := &PluginInfoCommand{
+		Client: &fakeServerClient{err: errors.New("unavailable")},
+		Out:    out,
+		Err:    errOut,
+	}
+	assert.Equal(t, -1, cmd.Run([]string{"-output", "json"}))
+	assert.Empty(t, out.String())
+	assert.JSONEq(t, `{"error": "error: unavailable"}`, errOut.String())
+}
@@ -3,6 +3,9 @@ package command
 import (
 	"crypto/tls"
 	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"reflect"
@@ -14,6 +17,8 @@ import (
 
 	"log"
 
+	"github.com/spiffe/spire/pkg/common/output"
+	"github.com/spiffe/spire/pkg/common/profile"
 	"github.com/spiffe/spire/proto/api/registration"
 	"github.com/spiffe/spire/proto/common"
 )
@@ -24,29 +29,66 @@ const (
 
 type RegisterCommand struct {
 	Client registration.RegistrationClient
+
+	// Out and Err default to stdout and stderr
+	Out io.Writer
+	Err io.Writer
+}
+
+// entryOutput is the JSON form of an entry created by the command
+type entryOutput struct {
+	EntryID  string `json:"entry_id"`
+	SpiffeID string `json:"spiffe_id"`
+	ParentID string `json:"parent_id"`
 }
 
 func (*RegisterCommand) Help() string {
-	return "Usage: spire-server register <data-file>"
+	return "Usage: spire-server register [-output <text|json>] [-profile <name>] <data-file>"
 }
 
 func (c *RegisterCommand) Run(args []string) int {
+	flags := flag.NewFlagSet("register", flag.ContinueOnError)
+	format := output.AddFlag(flags)
+	profileName := profile.AddFlag(flags)
+
+	if c.Err == nil {
+		c.Err = os.Stderr
+	}
+	flags.SetOutput(c.Err)
+
+	err := flags.Parse(args)
+	if err != nil {
+		return -1
+	}
+
+	err = profile.Apply(flags, profile.DefaultPath(), *profileName)
+	if err != nil {
+		fmt.Fprintln(c.Err, err.Error())
+		return -1
+	}
+
+	p, err := output.NewPrinter(*format, c.Out, c.Err)
+	if err != nil {
+		p.PrintError(err)
+		return -1
+	}
+
 	if c.Client == nil {
 		err := c.initializeGrpcClient(apiAddress)
 		if err != nil {
-			log.Fatalf("Failed: %v", err)
+			p.PrintError(fmt.Errorf("Failed: %v", err))
 			return -1
 		}
 	}
 
 	// Get filename
-	if len(args) != 1 {
-		log.Fatalf("Exactly one argument expected but got %d", len(args))
+	if flags.NArg() != 1 {
+		p.PrintError(fmt.Errorf("Exactly one argument expected but got %d", flags.NArg()))
 		return -1
 	}
-	dataFile := args[0]
+	dataFile := flags.Arg(0)
 	if _, err := os.Stat(dataFile); os.IsNotExist(err) {
-		log.Fatalf("File not found: %s", dataFile)
+		p.PrintError(fmt.Errorf("File not found: %s", dataFile))
 		return -1
 	}
 
@@ -54,31 +96,52 @@ func (c *RegisterCommand) Run(args []string) int {
 	entries := &common.RegistrationEntries{}
 	dat, err := ioutil.ReadFile(dataFile)
 	if err != nil {
-		log.Fatalf("Failed: %v", err)
+		p.PrintError(fmt.Errorf("Failed: %v", err))
 		return -1
 	}
 
 	json.Unmarshal(dat, &entries)
 
 	// Inject each entry and verify it
+	var registered []entryOutput
 	for index, registeredEntry := range entries.Entries {
-		log.Printf("Creating entry #%d...\n", index+1)
+		if !p.IsJSON() {
+			log.Printf("Creating entry #%d...\n", index+1)
+		}
 		entityID, err := c.createEntry(registeredEntry)
 		if err != nil {
-			log.Fatalf("Failed: %v", err)
+			p.PrintError(fmt.Errorf("Failed: %v", err))
 			return -1
 		}
 		valid, err := c.validateEntry(entityID, registeredEntry)
 		if err != nil {
-			log.Fatalf("Failed: %v", err)
+			p.PrintError(fmt.Errorf("Failed: %v", err))
 			return -1
 		}
-		if valid {
+		if !valid {
+			p.PrintError(fmt.Errorf("Fetched entity %s mismatch! Aborting...", entityID))
+			return -1
+		}
+		if !p.IsJSON() {
 			log.Printf("Fetched entity %s is OK!\n\n", entityID)
-		} else {
-			log.Printf("Fetched entity %s mismatch! Aborting...\n", entityID)
+		}
+
+		registered = append(registered, entryOutput{
+			EntryID:  entityID,
+			SpiffeID: registeredEntry.SpiffeId,
+			ParentID: registeredEntry.ParentId,
+		})
+	}
+
+	if p.IsJSON() {
+		err = p.PrintJSON(struct {
+			Entries []entryOutput `json:"entries"`
+		}{registered})
+		if err != nil {
+			p.PrintError(err)
 			return -1
 		}
+		return 0
 	}
 
 	log.Printf("Registration OK!\n")
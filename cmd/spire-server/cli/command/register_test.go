@@ -1,6 +1,8 @@
 package command
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/spiffe/spire/proto/common"
 	"github.com/spiffe/spire/test/mock/server/proto"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TODO: Test additional scenarios
@@ -70,3 +73,69 @@ func TestRegisterCommand_DataOK(t *testing.T) {
 	retval := regcmd.Run([]string{"../../../../test/fixture/registration/registration_good.json"})
 	assert.Equal(t, retval, 0)
 }
+
+func TestRegisterCommand_JSON(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockClient := mocks.NewMockRegistrationClient(mockCtrl)
+	ctx := context.Background()
+
+	var expected []entryOutput
+	for _, name := range []string{"Blog", "Database"} {
+		entry := &common.RegistrationEntry{
+			Selectors: []*common.Selector{
+				&common.Selector{
+					Type:  "unix",
+					Value: "uid:1111",
+				},
+			},
+			SpiffeId: "spiffe://example.org/" + name,
+			ParentId: "spiffe://example.org/spiffe/node-id/Token" + name,
+			Ttl:      200,
+		}
+		retID := &registration.RegistrationEntryID{
+			Id: fmt.Sprint(rand.Int()),
+		}
+		mockClient.EXPECT().CreateEntry(ctx, entry).Return(retID, nil)
+		mockClient.EXPECT().FetchEntry(ctx, retID).Return(entry, nil)
+
+		expected = append(expected, entryOutput{
+			EntryID:  retID.Id,
+			SpiffeID: entry.SpiffeId,
+			ParentID: entry.ParentId,
+		})
+	}
+
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	regcmd := &RegisterCommand{
+		Client: mockClient,
+		Out:    out,
+		Err:    errOut,
+	}
+	retval := regcmd.Run([]string{"-output", "json", "../../../../test/fixture/registration/registration_good.json"})
+	require.Equal(t, 0, retval)
+	assert.Empty(t, errOut.String())
+
+	var result struct {
+		Entries []entryOutput `json:"entries"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &result))
+	assert.Equal(t, expected, result.Entries)
+}
+
+func TestRegisterCommand_JSONError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	regcmd := &RegisterCommand{
+		Client: mocks.NewMockRegistrationClient(mockCtrl),
+		Out:    out,
+		Err:    errOut,
+	}
+	retval := regcmd.Run([]string{"-output", "json", "does-not-exist.json"})
+	assert.Equal(t, -1, retval)
+	assert.Empty(t, out.String())
+	assert.JSONEq(t, `{"error": "File not found: does-not-exist.json"}`, errOut.String())
+}
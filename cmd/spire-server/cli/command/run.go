@@ -9,7 +9,9 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"regexp"
 	"syscall"
+	"time"
 
 	"github.com/hashicorp/hcl"
 	"github.com/spiffe/spire/pkg/common/log"
@@ -22,6 +24,7 @@ const (
 	defaultBindPort        = "8081"
 	defaultBindHTTPPort    = "8080"
 	defaultLogLevel        = "INFO"
+	defaultLogFormat       = "text"
 	defaultPluginDir       = "conf/plugin/server"
 	defaultBaseSpiffeIDTTL = 999999
 )
@@ -35,7 +38,19 @@ type CmdConfig struct {
 	PluginDir       string
 	LogFile         string
 	LogLevel        string
+	LogFormat       string
 	BaseSpiffeIDTTL int
+
+	MaxConcurrentRequests int
+	RequestTimeout        string
+
+	// Only settable from the config file
+	MethodRequestTimeouts map[string]string
+
+	SpiffeIDPathPattern string
+
+	PruneInterval         string
+	AttestedNodeRetention string
 }
 
 //RunCommand itself
@@ -115,7 +130,13 @@ func setOptsFromCLI(c *server.Config, args []string) error {
 	flags.StringVar(&cmdConfig.PluginDir, "pluginDir", "", "Plugin conf.d configuration directory")
 	flags.StringVar(&cmdConfig.LogFile, "logFile", "", "File to write logs to")
 	flags.StringVar(&cmdConfig.LogLevel, "logLevel", "", "DEBUG, INFO, WARN or ERROR")
+	flags.StringVar(&cmdConfig.LogFormat, "logFormat", "", "text or json")
 	flags.IntVar(&cmdConfig.BaseSpiffeIDTTL, "baseSpiffeIDTTL", 0, "TTL to use when creating the baseSpiffeID")
+	flags.IntVar(&cmdConfig.MaxConcurrentRequests, "maxConcurrentRequests", 0, "Maximum number of API requests handled at once")
+	flags.StringVar(&cmdConfig.RequestTimeout, "requestTimeout", "", "Deadline applied to each API request, e.g. 30s")
+	flags.StringVar(&cmdConfig.SpiffeIDPathPattern, "spiffeIDPathPattern", "", "Regular expression the paths of registered SPIFFE IDs must match")
+	flags.StringVar(&cmdConfig.PruneInterval, "pruneInterval", "", "How often expired attested nodes are pruned, e.g. 1h")
+	flags.StringVar(&cmdConfig.AttestedNodeRetention, "attestedNodeRetention", "", "How long attested nodes are kept after their SVID expires, e.g. 720h")
 
 	err := flags.Parse(args)
 	if err != nil {
@@ -157,16 +178,76 @@ func mergeServerConfig(orig *server.Config, cmd *CmdConfig) error {
 		orig.PluginDir = cmd.PluginDir
 	}
 
+	if cmd.MaxConcurrentRequests != 0 {
+		orig.MaxConcurrentRequests = cmd.MaxConcurrentRequests
+	}
+
+	if cmd.RequestTimeout != "" {
+		timeout, err := time.ParseDuration(cmd.RequestTimeout)
+		if err != nil {
+			return fmt.Errorf("Could not parse RequestTimeout: %s", err)
+		}
+
+		orig.RequestTimeout = timeout
+	}
+
+	if len(cmd.MethodRequestTimeouts) > 0 {
+		timeouts := make(map[string]time.Duration)
+		for method, value := range cmd.MethodRequestTimeouts {
+			timeout, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("Could not parse MethodRequestTimeouts for %s: %s", method, err)
+			}
+
+			timeouts[method] = timeout
+		}
+
+		orig.MethodRequestTimeouts = timeouts
+	}
+
+	if cmd.SpiffeIDPathPattern != "" {
+		// The pattern must match the whole path, not just part of it
+		pattern, err := regexp.Compile("^(?:" + cmd.SpiffeIDPathPattern + ")$")
+		if err != nil {
+			return fmt.Errorf("Could not parse SpiffeIDPathPattern: %s", err)
+		}
+
+		orig.SpiffeIDPathPattern = pattern
+	}
+
+	if cmd.PruneInterval != "" {
+		interval, err := time.ParseDuration(cmd.PruneInterval)
+		if err != nil {
+			return fmt.Errorf("Could not parse PruneInterval: %s", err)
+		}
+
+		orig.PruneInterval = interval
+	}
+
+	if cmd.AttestedNodeRetention != "" {
+		retention, err := time.ParseDuration(cmd.AttestedNodeRetention)
+		if err != nil {
+			return fmt.Errorf("Could not parse AttestedNodeRetention: %s", err)
+		}
+
+		orig.AttestedNodeRetention = retention
+	}
+
 	// Handle log file and level
-	if cmd.LogFile != "" || cmd.LogLevel != "" {
+	if cmd.LogFile != "" || cmd.LogLevel != "" || cmd.LogFormat != "" {
 		logLevel := defaultLogLevel
 		if cmd.LogLevel != "" {
 			logLevel = cmd.LogLevel
 		}
 
-		logger, err := log.NewLogger(logLevel, cmd.LogFile)
+		logFormat := defaultLogFormat
+		if cmd.LogFormat != "" {
+			logFormat = cmd.LogFormat
+		}
+
+		logger, err := log.NewLogger(logLevel, logFormat, cmd.LogFile)
 		if err != nil {
-			return fmt.Errorf("Could not open log file %s: %s", cmd.LogFile, err)
+			return err
 		}
 
 		orig.Log = logger
@@ -188,6 +269,14 @@ func validateConfig(c *server.Config) error {
 		return errors.New("TrustDomain is required")
 	}
 
+	if c.MaxConcurrentRequests < 0 {
+		return errors.New("MaxConcurrentRequests must not be negative")
+	}
+
+	if c.AttestedNodeRetention < 0 {
+		return errors.New("AttestedNodeRetention must not be negative")
+	}
+
 	return nil
 }
 
@@ -196,7 +285,7 @@ func newDefaultConfig() *server.Config {
 	shutdownCh := make(chan struct{})
 
 	// log.NewLogger() cannot return error when using STDOUT
-	logger, _ := log.NewLogger(defaultLogLevel, "")
+	logger, _ := log.NewLogger(defaultLogLevel, defaultLogFormat, "")
 	bindAddress := &net.TCPAddr{}
 	serverHTTPAddress := &net.TCPAddr{}
 
@@ -21,6 +21,7 @@ import (
 	"github.com/spiffe/spire/pkg/agent/auth"
 	"github.com/spiffe/spire/pkg/agent/cache"
 	"github.com/spiffe/spire/pkg/agent/catalog"
+	"github.com/spiffe/spire/pkg/common/systemd"
 	"github.com/spiffe/spire/proto/agent/keymanager"
 	"github.com/spiffe/spire/proto/agent/nodeattestor"
 	"github.com/spiffe/spire/proto/api/node"
@@ -29,11 +30,16 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/peer"
 
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
 	spiffe_tls "github.com/spiffe/go-spiffe/tls"
 )
 
+const workloadServiceName = "spire.api.workload.Workload"
+
 type Config struct {
 	// Address to bind the workload api to
 	BindAddress *net.UnixAddr
@@ -61,6 +67,10 @@ type Config struct {
 	// Trust domain and associated CA bundle
 	TrustDomain url.URL
 	TrustBundle *x509.CertPool
+
+	// Fraction of an SVID's remaining lifetime after which workloads
+	// are told to fetch a new one
+	RotationFraction float64
 }
 
 type Agent struct {
@@ -69,6 +79,7 @@ type Agent struct {
 	BaseSVIDTTL int32
 	config      *Config
 	grpcServer  *grpc.Server
+	health      *health.Server
 	Cache       cache.Cache
 	Catalog     catalog.Catalog
 	serverCerts []*x509.Certificate
@@ -103,25 +114,43 @@ func (a *Agent) Run() error {
 		return err
 	}
 
+	watchdog, stopWatchdog, err := systemd.WatchdogTicker()
+	if err != nil {
+		a.config.Log.Warnf("Not sending systemd watchdog keep-alives: %s", err)
+	}
+	defer stopWatchdog()
+
+	a.notifySystemd(systemd.Ready)
+
 	// Main event loop
 	a.config.Log.Info("SPIRE Agent is now running")
 	for {
 		select {
 		case err = <-a.config.ErrorCh:
+			a.notifySystemd(systemd.Stopping)
 			return err
 		case <-a.config.ShutdownCh:
 			return a.Shutdown()
+		case <-watchdog:
+			a.notifySystemd(systemd.Watchdog)
 		}
 	}
 }
 
 func (a *Agent) Shutdown() error {
+	a.notifySystemd(systemd.Stopping)
+
+	// Report as not serving while in-flight requests drain, and only stop
+	// the plugins once those requests are done with them
+	a.health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	a.health.SetServingStatus(workloadServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+
+	a.grpcServer.GracefulStop()
+
 	if a.Catalog != nil {
 		a.Catalog.Stop()
 	}
 
-	a.grpcServer.GracefulStop()
-
 	// Drain error channel, last one wins
 	var err error
 Drain:
@@ -137,6 +166,13 @@ Drain:
 	return err
 }
 
+func (a *Agent) notifySystemd(state string) {
+	err := systemd.Notify(state)
+	if err != nil {
+		a.config.Log.Warnf("Unable to notify systemd of state %s: %s", state, err)
+	}
+}
+
 func (a *Agent) initPlugins() error {
 	err := a.Catalog.Run()
 	if err != nil {
@@ -149,21 +185,28 @@ func (a *Agent) initPlugins() error {
 func (a *Agent) initEndpoints() error {
 	a.config.Log.Info("Starting the workload API")
 
-	maxWorkloadTTL := time.Duration(a.BaseSVIDTTL/2) * time.Second
-
 	log := a.config.Log.WithField("subsystem_name", "workload")
 	ws := &workloadServer{
 		bundle:  a.serverCerts[1].Raw, // TODO: Fix handling of serverCerts
 		cache:   a.Cache,
 		catalog: a.Catalog,
 		l:       log,
-		maxTTL:  maxWorkloadTTL,
+		maxTTL:  maxWorkloadTTL(a.BaseSVIDTTL, a.config.RotationFraction),
+
+		rotationFraction: a.config.RotationFraction,
 	}
 
 	// Create a gRPC server with our custom "credential" resolver
 	a.grpcServer = grpc.NewServer(grpc.Creds(auth.NewCredentials()))
 	workload.RegisterWorkloadServer(a.grpcServer, ws)
 
+	// Serve the standard gRPC health service alongside the Workload API,
+	// so that readiness can be probed without fetching an SVID
+	a.health = health.NewServer()
+	a.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	a.health.SetServingStatus(workloadServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(a.grpcServer, a.health)
+
 	addr := a.config.BindAddress
 	if addr.Network() == "unix" {
 		_ = os.Remove(addr.String())
@@ -181,6 +224,15 @@ func (a *Agent) initEndpoints() error {
 	return nil
 }
 
+// maxWorkloadTTL returns the longest TTL to hand out in Workload API
+// responses, the rotation fraction of the base SVID TTL. It bounds how long
+// a workload waits before checking back, even when its own SVIDs expire
+// later. The agent does not rotate its base SVID, this is only a hint to
+// workloads.
+func maxWorkloadTTL(baseSVIDTTL int32, rotationFraction float64) time.Duration {
+	return time.Duration(float64(baseSVIDTTL)*rotationFraction) * time.Second
+}
+
 func (a *Agent) bootstrap() error {
 	a.config.Log.Info("Bootstrapping SPIRE agent")
 
@@ -431,10 +483,16 @@ func (a *Agent) FetchSVID(registrationEntryMap map[string]*common.RegistrationEn
 
 		if len(resp.SvidUpdate.RegistrationEntries) != 0 {
 			for _, entry := range resp.SvidUpdate.RegistrationEntries {
+				// The server may decline to sign some of the CSRs
+				svid, ok := svidMap[entry.SpiffeId]
+				if !ok {
+					continue
+				}
+
 				if _, ok := registrationEntryMap[entry.SpiffeId]; ok != true {
 					newRegistrationMap[entry.SpiffeId] = entry
 				}
-				a.FetchSVID(newRegistrationMap, svidMap[entry.SpiffeId].SvidCert, pkeyMap[entry.SpiffeId])
+				a.FetchSVID(newRegistrationMap, svid.SvidCert, pkeyMap[entry.SpiffeId])
 
 			}
 
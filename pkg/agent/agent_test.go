@@ -6,16 +6,25 @@ import (
 	"crypto/rand"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"io/ioutil"
 	"net"
 	"os"
+	"path"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/spire/pkg/agent/cache"
 	"github.com/spiffe/spire/proto/agent/keymanager"
 	"github.com/spiffe/spire/proto/common"
 	"github.com/spiffe/spire/test/mock/agent/catalog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 type selectors []*common.Selector
@@ -101,3 +110,86 @@ func TestAgent_FetchSVID(t *testing.T) {
 		})
 	}
 }
+
+func TestInitEndpoints_Health(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spire-agent-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	l, _ := test.NewNullLogger()
+	socketPath := path.Join(dir, "spire_api")
+	a := &Agent{
+		Cache: cache.NewCache(),
+		config: &Config{
+			BindAddress:      &net.UnixAddr{Name: socketPath, Net: "unix"},
+			Log:              l,
+			ErrorCh:          make(chan error, 1),
+			RotationFraction: 0.5,
+		},
+		serverCerts: []*x509.Certificate{{}, {Raw: []byte("bundle")}},
+	}
+	require.NoError(t, a.initEndpoints())
+	defer a.grpcServer.Stop()
+
+	dialer := func(addr string, timeout time.Duration) (net.Conn, error) {
+		return net.DialTimeout("unix", addr, timeout)
+	}
+	conn, err := grpc.Dial(socketPath, grpc.WithInsecure(), grpc.WithDialer(dialer))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// The empty service name is what most probes ask for by default
+	client := healthpb.NewHealthClient(conn)
+	for _, service := range []string{"", workloadServiceName} {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	}
+}
+
+func TestShutdown_Order(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spire-agent-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	l, _ := test.NewNullLogger()
+	a := &Agent{
+		Cache: cache.NewCache(),
+		config: &Config{
+			BindAddress:      &net.UnixAddr{Name: path.Join(dir, "spire_api"), Net: "unix"},
+			Log:              l,
+			ErrorCh:          make(chan error, 1),
+			RotationFraction: 0.5,
+		},
+		serverCerts: []*x509.Certificate{{}, {Raw: []byte("bundle")}},
+	}
+	require.NoError(t, a.initEndpoints())
+
+	dialer := func(addr string, timeout time.Duration) (net.Conn, error) {
+		return net.DialTimeout("unix", addr, timeout)
+	}
+	conn, err := grpc.Dial(a.config.BindAddress.String(), grpc.WithInsecure(), grpc.WithDialer(dialer), grpc.WithBlock())
+	require.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	// By the time the plugins stop, health reports not serving and the
+	// server is done with its requests
+	mockCatalog := mock_catalog.NewMockCatalog(mockCtrl)
+	mockCatalog.EXPECT().Stop().Do(func() {
+		for _, service := range []string{"", workloadServiceName} {
+			resp, err := a.health.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+			require.NoError(t, err)
+			assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+		}
+
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		assert.Error(t, err, "Workload API still serving")
+	})
+	a.Catalog = mockCatalog
+
+	a.Shutdown()
+}
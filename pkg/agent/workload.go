@@ -29,6 +29,10 @@ type workloadServer struct {
 	// be larger than this
 	maxTTL time.Duration
 
+	// Fraction of the remaining SVID lifetime
+	// to use as the TTL in SVID responses
+	rotationFraction float64
+
 	// We must store the current server bundle for
 	// distrubution to workloads. It is updaetd periodically,
 	// protect it with a mutex.
@@ -208,7 +212,7 @@ func (s *workloadServer) composeResponse(entries []cache.CacheEntry) (response *
 	// check back after TTL
 	minTTL := s.maxTTL
 	for _, e := range expirys {
-		ttl := time.Until(e) / 2
+		ttl := time.Duration(float64(time.Until(e)) * s.rotationFraction)
 		if ttl < minTTL {
 			minTTL = ttl
 		}
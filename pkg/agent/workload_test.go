@@ -10,6 +10,8 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
 	"github.com/spiffe/spire/pkg/agent/cache"
@@ -63,6 +65,8 @@ func (s *WorkloadServerTestSuite) SetupTest() {
 		l:       log,
 		bundle:  []byte{},
 		maxTTL:  ttl,
+
+		rotationFraction: 0.5,
 	}
 
 	s.w = ws
@@ -180,6 +184,40 @@ func generateCacheEntry(spiffeID, parentID string, selectors selector.Set) (cach
 	return cacheEntry, nil
 }
 
+func TestComposeResponse_RotationFraction(t *testing.T) {
+	entry, err := generateCacheEntry("spiffe://example.org/bat", "spiffe://example.org/baz", selector.Set{selector1})
+	require.NoError(t, err)
+	entry.Expiry = time.Now().Add(time.Hour)
+
+	l, _ := test.NewNullLogger()
+	for _, tt := range []struct {
+		fraction float64
+		maxTTL   time.Duration
+		ttl      time.Duration
+	}{
+		{fraction: 0.25, maxTTL: 12 * time.Hour, ttl: 15 * time.Minute},
+		{fraction: 0.5, maxTTL: 12 * time.Hour, ttl: 30 * time.Minute},
+		{fraction: 0.75, maxTTL: 12 * time.Hour, ttl: 45 * time.Minute},
+		{fraction: 0.75, maxTTL: 10 * time.Minute, ttl: 10 * time.Minute},
+	} {
+		ws := &workloadServer{
+			l:                l,
+			maxTTL:           tt.maxTTL,
+			rotationFraction: tt.fraction,
+		}
+
+		resp, err := ws.composeResponse([]cache.CacheEntry{entry})
+		require.NoError(t, err)
+		assert.InDelta(t, tt.ttl.Seconds(), resp.Ttl, 2, "fraction %v", tt.fraction)
+	}
+}
+
+func TestMaxWorkloadTTL(t *testing.T) {
+	assert.Equal(t, 30*time.Minute, maxWorkloadTTL(3600, 0.5))
+	assert.Equal(t, 15*time.Minute, maxWorkloadTTL(3600, 0.25))
+	assert.Equal(t, 54*time.Minute, maxWorkloadTTL(3600, 0.9))
+}
+
 func TestWorkloadServer(t *testing.T) {
 	suite.Run(t, new(WorkloadServerTestSuite))
 }
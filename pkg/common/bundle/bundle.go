@@ -0,0 +1,130 @@
+// Package bundle fetches the CA bundle of the agent's trust domain from the
+// Workload API, and encodes it for the tooling which provisions trust stores,
+// either as PEM or as a JWK set.
+package bundle
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+
+	"golang.org/x/net/context"
+
+	"github.com/spiffe/spire/proto/api/workload"
+)
+
+// x509SVIDUse is the JWK "use" of the keys which sign X.509 SVIDs, as per
+// the SPIFFE trust domain and bundle specification
+const x509SVIDUse = "x509-svid"
+
+// Bundle is the set of CA certificates of a trust domain
+type Bundle struct {
+	TrustDomain  string
+	Certificates []*x509.Certificate
+}
+
+// JWKS is the JWK set form of a bundle
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK is a single key of a JWK set, with the certificate it came from
+type JWK struct {
+	KeyType string   `json:"kty"`
+	Use     string   `json:"use"`
+	Curve   string   `json:"crv,omitempty"`
+	X       string   `json:"x,omitempty"`
+	Y       string   `json:"y,omitempty"`
+	N       string   `json:"n,omitempty"`
+	E       string   `json:"e,omitempty"`
+	X5C     []string `json:"x5c"`
+}
+
+// Fetch returns the bundle of the agent's own trust domain. The caller must
+// be entitled to at least one registration entry.
+func Fetch(ctx context.Context, client workload.WorkloadClient) (*Bundle, error) {
+	resp, err := client.FetchAllBundles(ctx, &workload.Empty{})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Bundles) == 0 {
+		return nil, errors.New("No registration entries found for the caller")
+	}
+
+	entry := resp.Bundles[0]
+	if len(entry.SvidBundle) == 0 {
+		return nil, errors.New("The agent has no bundle for its own trust domain")
+	}
+
+	id, err := url.Parse(entry.SpiffeId)
+	if err != nil {
+		return nil, err
+	}
+
+	certs, err := x509.ParseCertificates(entry.SvidBundle)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse bundle: %s", err)
+	}
+
+	return &Bundle{TrustDomain: id.Host, Certificates: certs}, nil
+}
+
+// PEM returns the certificates of the bundle as PEM blocks
+func (b *Bundle) PEM() []byte {
+	buf := new(bytes.Buffer)
+	for _, cert := range b.Certificates {
+		pem.Encode(buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+
+	return buf.Bytes()
+}
+
+// JWKS returns the public keys of the bundle as a JWK set. It fails if a
+// certificate holds a key which is neither ECDSA nor RSA.
+func (b *Bundle) JWKS() (*JWKS, error) {
+	jwks := &JWKS{Keys: []JWK{}}
+	for _, cert := range b.Certificates {
+		key := JWK{
+			Use: x509SVIDUse,
+			X5C: []string{base64.StdEncoding.EncodeToString(cert.Raw)},
+		}
+
+		switch pub := cert.PublicKey.(type) {
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			key.KeyType = "EC"
+			key.Curve = pub.Curve.Params().Name
+			key.X = encodeInt(pub.X, size)
+			key.Y = encodeInt(pub.Y, size)
+		case *rsa.PublicKey:
+			key.KeyType = "RSA"
+			key.N = encodeInt(pub.N, 0)
+			key.E = encodeInt(big.NewInt(int64(pub.E)), 0)
+		default:
+			return nil, fmt.Errorf("Unsupported key type %T in certificate %s", pub, cert.Subject)
+		}
+
+		jwks.Keys = append(jwks.Keys, key)
+	}
+
+	return jwks, nil
+}
+
+// encodeInt returns the unpadded base64url encoding of the big endian bytes
+// of n, left padded with zeros to size bytes.
+func encodeInt(n *big.Int, size int) string {
+	b := n.Bytes()
+	if len(b) < size {
+		b = append(make([]byte, size-len(b)), b...)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
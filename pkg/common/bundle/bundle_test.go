@@ -0,0 +1,156 @@
+package bundle
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/spiffe/spire/proto/api/workload"
+)
+
+type fakeWorkloadClient struct {
+	bundles *workload.Bundles
+	err     error
+}
+
+func (c *fakeWorkloadClient) FetchBundles(ctx context.Context, in *workload.SpiffeID, opts ...grpc.CallOption) (*workload.Bundles, error) {
+	return c.bundles, c.err
+}
+
+func (c *fakeWorkloadClient) FetchAllBundles(ctx context.Context, in *workload.Empty, opts ...grpc.CallOption) (*workload.Bundles, error) {
+	return c.bundles, c.err
+}
+
+func TestFetch(t *testing.T) {
+	cert := createCertificate(t, ecKey(t))
+
+	client := &fakeWorkloadClient{
+		bundles: &workload.Bundles{
+			Bundles: []*workload.WorkloadEntry{
+				{SpiffeId: "spiffe://example.org/foo", SvidBundle: cert.Raw},
+			},
+		},
+	}
+
+	b, err := Fetch(context.Background(), client)
+	require.NoError(t, err)
+	assert.Equal(t, "example.org", b.TrustDomain)
+	require.Len(t, b.Certificates, 1)
+	assert.Equal(t, cert.Raw, b.Certificates[0].Raw)
+}
+
+func TestFetch_Errors(t *testing.T) {
+	_, err := Fetch(context.Background(), &fakeWorkloadClient{err: errors.New("foo")})
+	assert.EqualError(t, err, "foo")
+
+	_, err = Fetch(context.Background(), &fakeWorkloadClient{bundles: &workload.Bundles{}})
+	assert.EqualError(t, err, "No registration entries found for the caller")
+
+	client := &fakeWorkloadClient{
+		bundles: &workload.Bundles{
+			Bundles: []*workload.WorkloadEntry{{SpiffeId: "spiffe://example.org/foo"}},
+		},
+	}
+	_, err = Fetch(context.Background(), client)
+	assert.EqualError(t, err, "The agent has no bundle for its own trust domain")
+
+	client.bundles.Bundles[0].SvidBundle = []byte("foo")
+	_, err = Fetch(context.Background(), client)
+	assert.Error(t, err)
+}
+
+func TestBundle_PEM(t *testing.T) {
+	cert1 := createCertificate(t, ecKey(t))
+	cert2 := createCertificate(t, ecKey(t))
+
+	b := &Bundle{Certificates: []*x509.Certificate{cert1, cert2}}
+
+	block, rest := pem.Decode(b.PEM())
+	require.NotNil(t, block)
+	assert.Equal(t, cert1.Raw, block.Bytes)
+
+	block, rest = pem.Decode(rest)
+	require.NotNil(t, block)
+	assert.Equal(t, cert2.Raw, block.Bytes)
+	assert.Empty(t, rest)
+}
+
+func TestBundle_JWKS(t *testing.T) {
+	ecPriv := ecKey(t)
+	rsaPriv, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	ecCert := createCertificate(t, ecPriv)
+	rsaCert := createCertificate(t, rsaPriv)
+
+	b := &Bundle{Certificates: []*x509.Certificate{ecCert, rsaCert}}
+	jwks, err := b.JWKS()
+	require.NoError(t, err)
+	require.Len(t, jwks.Keys, 2)
+
+	ec := jwks.Keys[0]
+	assert.Equal(t, "EC", ec.KeyType)
+	assert.Equal(t, "x509-svid", ec.Use)
+	assert.Equal(t, "P-256", ec.Curve)
+	assert.Equal(t, ecPriv.X, decodeInt(t, ec.X, 32))
+	assert.Equal(t, ecPriv.Y, decodeInt(t, ec.Y, 32))
+	assert.Equal(t, []string{base64.StdEncoding.EncodeToString(ecCert.Raw)}, ec.X5C)
+
+	r := jwks.Keys[1]
+	assert.Equal(t, "RSA", r.KeyType)
+	assert.Equal(t, "x509-svid", r.Use)
+	assert.Equal(t, rsaPriv.N, decodeInt(t, r.N, 128))
+	assert.Equal(t, "AQAB", r.E)
+	assert.Equal(t, []string{base64.StdEncoding.EncodeToString(rsaCert.Raw)}, r.X5C)
+}
+
+func TestBundle_JWKSEmpty(t *testing.T) {
+	jwks, err := (&Bundle{}).JWKS()
+	require.NoError(t, err)
+	assert.NotNil(t, jwks.Keys)
+	assert.Empty(t, jwks.Keys)
+}
+
+func ecKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func createCertificate(t *testing.T, key crypto.Signer) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
+
+func decodeInt(t *testing.T, s string, size int) *big.Int {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	require.NoError(t, err)
+	assert.Len(t, b, size)
+	return new(big.Int).SetBytes(b)
+}
@@ -1,33 +1,47 @@
 package log
 
 import (
+	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 )
 
-func NewLogger(logLevel string, fileName string) (logrus.FieldLogger, error) {
-	var fd io.Writer
-	var err error
+// NewLogger returns a logger writing to the given file, or to stdout if
+// fileName is empty. logFormat is either "text" or "json", and defaults
+// to text when empty.
+func NewLogger(logLevel string, logFormat string, fileName string) (logrus.FieldLogger, error) {
+	logrusLevel, err := logrus.ParseLevel(logLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	var formatter logrus.Formatter
+	switch strings.ToLower(logFormat) {
+	case "", "text":
+		formatter = &logrus.TextFormatter{}
+	case "json":
+		formatter = &logrus.JSONFormatter{}
+	default:
+		return nil, fmt.Errorf("Unknown log format: %s", logFormat)
+	}
 
+	var fd io.Writer
 	if fileName != "" {
 		fd, err = os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("Could not open log file %s: %s", fileName, err)
 		}
 	} else {
 		fd = os.Stdout
 	}
 
-	logrusLevel, err := logrus.ParseLevel(logLevel)
-	if err != nil {
-		return nil, err
-	}
-
 	logger := logrus.New()
 	logger.Out = fd
 	logger.Level = logrusLevel
+	logger.Formatter = formatter
 
 	return logger, nil
 }
@@ -0,0 +1,54 @@
+package log
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogger_JSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spire-log-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fileName := path.Join(dir, "spire.log")
+	logger, err := NewLogger("INFO", "json", fileName)
+	require.NoError(t, err)
+
+	logger.WithField("subsystem_name", "test").Info("hello")
+
+	data, err := ioutil.ReadFile(fileName)
+	require.NoError(t, err)
+
+	entry := make(map[string]interface{})
+	require.NoError(t, json.Unmarshal(data, &entry))
+	assert.Equal(t, "hello", entry["msg"])
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "test", entry["subsystem_name"])
+}
+
+func TestNewLogger_UnknownFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spire-log-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The error is about the format, and no file is left behind
+	fileName := path.Join(dir, "spire.log")
+	_, err = NewLogger("INFO", "xml", fileName)
+	require.Error(t, err)
+	assert.Equal(t, "Unknown log format: xml", err.Error())
+
+	_, err = os.Stat(fileName)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestNewLogger_FileError(t *testing.T) {
+	_, err := NewLogger("INFO", "text", "/nonexistent/spire.log")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Could not open log file /nonexistent/spire.log")
+}
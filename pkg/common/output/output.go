@@ -0,0 +1,77 @@
+// Package output writes the results of CLI commands, either as text meant
+// for people or as JSON meant for scripts. Results go to one writer and
+// errors to another, so that errors never corrupt a redirected result.
+package output
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	Text = "text"
+	JSON = "json"
+)
+
+// Printer writes the results and errors of a command in one format
+type Printer struct {
+	format string
+	out    io.Writer
+	err    io.Writer
+}
+
+// AddFlag registers the -output flag on the given flag set
+func AddFlag(flags *flag.FlagSet) *string {
+	return flags.String("output", Text, "Output format, text or json")
+}
+
+// NewPrinter returns a printer for the given format. Nil writers default to
+// stdout and stderr respectively.
+func NewPrinter(format string, out, err io.Writer) (*Printer, error) {
+	if out == nil {
+		out = os.Stdout
+	}
+	if err == nil {
+		err = os.Stderr
+	}
+
+	p := &Printer{format: format, out: out, err: err}
+	if format != Text && format != JSON {
+		// Fall back to text so that the error itself can be printed
+		p.format = Text
+		return p, fmt.Errorf("Unknown output format: %s", format)
+	}
+
+	return p, nil
+}
+
+// IsJSON reports whether results should be printed as JSON
+func (p *Printer) IsJSON() bool {
+	return p.format == JSON
+}
+
+// Out returns the writer for text results
+func (p *Printer) Out() io.Writer {
+	return p.out
+}
+
+// PrintJSON writes v as a single line of JSON
+func (p *Printer) PrintJSON(v interface{}) error {
+	return json.NewEncoder(p.out).Encode(v)
+}
+
+// PrintError writes err as a line of text, or as a JSON object with an
+// "error" field.
+func (p *Printer) PrintError(err error) {
+	if p.IsJSON() {
+		json.NewEncoder(p.err).Encode(struct {
+			Error string `json:"error"`
+		}{err.Error()})
+		return
+	}
+
+	fmt.Fprintln(p.err, err.Error())
+}
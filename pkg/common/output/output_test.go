@@ -0,0 +1,54 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrinter_Text(t *testing.T) {
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	p, err := NewPrinter(Text, out, errOut)
+	require.NoError(t, err)
+	assert.False(t, p.IsJSON())
+
+	p.PrintError(errors.New("foo"))
+	assert.Equal(t, "foo\n", errOut.String())
+	assert.Empty(t, out.String())
+}
+
+func TestPrinter_JSON(t *testing.T) {
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	p, err := NewPrinter(JSON, out, errOut)
+	require.NoError(t, err)
+	assert.True(t, p.IsJSON())
+
+	require.NoError(t, p.PrintJSON(map[string]string{"foo": "bar"}))
+	assert.Equal(t, `{"foo":"bar"}`+"\n", out.String())
+
+	p.PrintError(errors.New("foo"))
+	assert.Equal(t, `{"error":"foo"}`+"\n", errOut.String())
+}
+
+func TestPrinter_UnknownFormat(t *testing.T) {
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	p, err := NewPrinter("xml", out, errOut)
+	require.Error(t, err)
+
+	// The returned printer can still report the error
+	p.PrintError(err)
+	assert.Equal(t, "Unknown output format: xml\n", errOut.String())
+}
+
+func TestAddFlag(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	format := AddFlag(flags)
+	assert.Equal(t, Text, *format)
+
+	require.NoError(t, flags.Parse([]string{"-output", "json"}))
+	assert.Equal(t, JSON, *format)
+}
@@ -0,0 +1,106 @@
+// Package profile lets CLI commands take default flag values from named
+// profiles in a config file, so that operators managing several trust
+// domains don't repeat the same flags on every invocation. The file is HCL:
+//
+//	profile "default" {
+//	  output = "json"
+//	}
+//
+//	profile "prod" {
+//	  socket_path = "/run/spire/prod/agent.sock"
+//	}
+//
+// Flags given on the command line take precedence over the profile.
+package profile
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/spiffe/spire/pkg/common/config"
+)
+
+// Default is the profile used when none is selected
+const Default = "default"
+
+// Profile holds the default values of the flags a profile can set
+type Profile struct {
+	SocketPath string `hcl:"socket_path"`
+	Output     string `hcl:"output"`
+}
+
+// flagValues maps the name of each flag a profile can set to its value
+func (p *Profile) flagValues() map[string]string {
+	return map[string]string{
+		"socketPath": p.SocketPath,
+		"output":     p.Output,
+	}
+}
+
+type fileConfig struct {
+	Profiles map[string]*Profile `hcl:"profile"`
+}
+
+// DefaultPath returns the location of the CLI config file, ~/.spire/cli
+func DefaultPath() string {
+	return path.Join(os.Getenv("HOME"), ".spire", "cli")
+}
+
+// AddFlag registers the -profile flag on the given flag set
+func AddFlag(flags *flag.FlagSet) *string {
+	return flags.String("profile", "", "Name of the CLI profile to take default flag values from")
+}
+
+// Load reads the named profile from the config file at the given path. If
+// no name is given the default profile is returned, or nil if the file or
+// the default profile does not exist.
+func Load(configPath, name string) (*Profile, error) {
+	c := &fileConfig{}
+	err := config.ParseHCLFile(configPath, c)
+	switch {
+	case os.IsNotExist(err) && name == "":
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("Could not load CLI config %s: %s", configPath, err)
+	}
+
+	if name == "" {
+		return c.Profiles[Default], nil
+	}
+
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("Profile %s not found in %s", name, configPath)
+	}
+
+	return p, nil
+}
+
+// Apply sets the flags which were not given on the command line from the
+// named profile. It must be called after the flags are parsed. Profile
+// values for flags the command does not have are ignored.
+func Apply(flags *flag.FlagSet, configPath, name string) error {
+	p, err := Load(configPath, name)
+	if err != nil || p == nil {
+		return err
+	}
+
+	given := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	for flagName, value := range p.flagValues() {
+		if value == "" || given[flagName] || flags.Lookup(flagName) == nil {
+			continue
+		}
+
+		if err := flags.Set(flagName, value); err != nil {
+			return fmt.Errorf("Invalid value %q for %s in profile: %s", value, flagName, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package profile
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+profile "default" {
+  output = "json"
+}
+
+profile "prod" {
+  socket_path = "/run/spire/prod/agent.sock"
+  output = "text"
+}
+`
+
+func writeConfig(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "spire-profile-test")
+	require.NoError(t, err)
+
+	configPath := path.Join(dir, "cli")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(content), 0600))
+
+	return configPath, func() { os.RemoveAll(dir) }
+}
+
+func newFlags() (*flag.FlagSet, *string, *string) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	socketPath := flags.String("socketPath", "/tmp/agent.sock", "")
+	output := flags.String("output", "text", "")
+	return flags, socketPath, output
+}
+
+func TestApply_NamedProfile(t *testing.T) {
+	configPath, cleanup := writeConfig(t, testConfig)
+	defer cleanup()
+
+	flags, socketPath, output := newFlags()
+	require.NoError(t, flags.Parse(nil))
+	require.NoError(t, Apply(flags, configPath, "prod"))
+
+	assert.Equal(t, "/run/spire/prod/agent.sock", *socketPath)
+	assert.Equal(t, "text", *output)
+}
+
+func TestApply_DefaultProfile(t *testing.T) {
+	configPath, cleanup := writeConfig(t, testConfig)
+	defer cleanup()
+
+	flags, socketPath, output := newFlags()
+	require.NoError(t, flags.Parse(nil))
+	require.NoError(t, Apply(flags, configPath, ""))
+
+	assert.Equal(t, "/tmp/agent.sock", *socketPath)
+	assert.Equal(t, "json", *output)
+}
+
+func TestApply_CommandLineWins(t *testing.T) {
+	configPath, cleanup := writeConfig(t, testConfig)
+	defer cleanup()
+
+	flags, socketPath, output := newFlags()
+	require.NoError(t, flags.Parse([]string{"-output", "text"}))
+	require.NoError(t, Apply(flags, configPath, "prod"))
+
+	assert.Equal(t, "/run/spire/prod/agent.sock", *socketPath)
+	assert.Equal(t, "text", *output)
+}
+
+func TestApply_UnknownFlagsIgnored(t *testing.T) {
+	configPath, cleanup := writeConfig(t, testConfig)
+	defer cleanup()
+
+	// A command without -socketPath can still use the profile
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	output := flags.String("output", "text", "")
+	require.NoError(t, flags.Parse(nil))
+	require.NoError(t, Apply(flags, configPath, ""))
+
+	assert.Equal(t, "json", *output)
+}
+
+func TestApply_MissingConfig(t *testing.T) {
+	configPath := path.Join(os.TempDir(), "spire-profile-test-missing")
+
+	flags, _, output := newFlags()
+	require.NoError(t, flags.Parse(nil))
+
+	// Without a config file there is nothing to apply, unless a profile
+	// was asked for
+	require.NoError(t, Apply(flags, configPath, ""))
+	assert.Equal(t, "text", *output)
+	assert.Error(t, Apply(flags, configPath, "prod"))
+}
+
+func TestApply_UnknownProfile(t *testing.T) {
+	configPath, cleanup := writeConfig(t, testConfig)
+	defer cleanup()
+
+	flags, _, _ := newFlags()
+	require.NoError(t, flags.Parse(nil))
+	assert.EqualError(t, Apply(flags, configPath, "staging"), "Profile staging not found in "+configPath)
+}
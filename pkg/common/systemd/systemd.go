@@ -0,0 +1,80 @@
+// Package systemd implements the parts of the systemd service notification
+// protocol used by SPIRE: readiness and stopping notifications, and watchdog
+// keep-alives. When the process is not supervised by a Type=notify unit, the
+// notification socket is not set and all functions are no-ops.
+package systemd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+// Notify sends the given state string to the service manager
+func Notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	// Sockets in the abstract namespace are prefixed with '@'
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+
+	addr := &net.UnixAddr{Name: socket, Net: "unixgram"}
+	conn, err := net.DialUnix(addr.Net, nil, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// WatchdogInterval returns the watchdog timeout configured by the service
+// manager for this process. Keep-alives must be sent more often than this.
+// A zero duration means the watchdog is disabled.
+func WatchdogInterval() (time.Duration, error) {
+	usec := os.Getenv("WATCHDOG_USEC")
+	if usec == "" {
+		return 0, nil
+	}
+
+	// The watchdog may be meant for another process
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+
+	n, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("Invalid WATCHDOG_USEC value: %s", usec)
+	}
+
+	return time.Duration(n) * time.Microsecond, nil
+}
+
+// WatchdogTicker returns a channel which fires at half the watchdog interval,
+// along with a function to stop it. Keep-alives should be sent from the main
+// event loop, so that a hung process stops sending them. The channel is nil if
+// the watchdog is not enabled, or if its configuration is invalid, in which
+// case the error is returned too.
+func WatchdogTicker() (<-chan time.Time, func(), error) {
+	interval, err := WatchdogInterval()
+	if err != nil || interval == 0 {
+		return nil, func() {}, err
+	}
+
+	ticker := time.NewTicker(interval / 2)
+	return ticker.C, ticker.Stop, nil
+}
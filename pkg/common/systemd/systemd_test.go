@@ -0,0 +1,93 @@
+package systemd
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotify(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spire-systemd-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	addr := &net.UnixAddr{Name: path.Join(dir, "notify.sock"), Net: "unixgram"}
+	conn, err := net.ListenUnixgram(addr.Net, addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	os.Setenv("NOTIFY_SOCKET", addr.Name)
+	defer os.Unsetenv("NOTIFY_SOCKET")
+
+	require.NoError(t, Notify(Ready))
+
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, Ready, string(buf[:n]))
+}
+
+func TestNotify_NoSocket(t *testing.T) {
+	os.Unsetenv("NOTIFY_SOCKET")
+	assert.NoError(t, Notify(Ready))
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	defer os.Unsetenv("WATCHDOG_USEC")
+	defer os.Unsetenv("WATCHDOG_PID")
+
+	os.Unsetenv("WATCHDOG_USEC")
+	interval, err := WatchdogInterval()
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), interval)
+
+	os.Setenv("WATCHDOG_USEC", "30000000")
+	interval, err = WatchdogInterval()
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, interval)
+
+	os.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()+1))
+	interval, err = WatchdogInterval()
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), interval)
+
+	os.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	os.Setenv("WATCHDOG_USEC", "foo")
+	_, err = WatchdogInterval()
+	assert.Error(t, err)
+}
+
+func TestWatchdogTicker(t *testing.T) {
+	defer os.Unsetenv("WATCHDOG_USEC")
+
+	os.Unsetenv("WATCHDOG_USEC")
+	ticker, stop, err := WatchdogTicker()
+	require.NoError(t, err)
+	assert.Nil(t, ticker)
+	stop()
+
+	os.Setenv("WATCHDOG_USEC", "foo")
+	ticker, stop, err = WatchdogTicker()
+	assert.Error(t, err)
+	assert.Nil(t, ticker)
+	stop()
+
+	os.Setenv("WATCHDOG_USEC", "20000")
+	ticker, stop, err = WatchdogTicker()
+	require.NoError(t, err)
+	defer stop()
+
+	select {
+	case <-ticker:
+	case <-time.After(time.Second):
+		t.Fatal("Watchdog ticker did not fire")
+	}
+}
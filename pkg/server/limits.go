@@ -0,0 +1,46 @@
+package server
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// limitRequests returns a unary interceptor which applies a deadline to each
+// request and bounds the number of requests handled concurrently. Requests
+// over the limit are rejected with RESOURCE_EXHAUSTED rather than queued, so
+// that clients back off instead of piling up on an overloaded server. Zero
+// values disable the respective limit. Deadlines in methodTimeouts, keyed by
+// full method name, take precedence over the default timeout.
+func limitRequests(maxConcurrent int, timeout time.Duration, methodTimeouts map[string]time.Duration) grpc.UnaryServerInterceptor {
+	var inFlight chan struct{}
+	if maxConcurrent > 0 {
+		inFlight = make(chan struct{}, maxConcurrent)
+	}
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if inFlight != nil {
+			select {
+			case inFlight <- struct{}{}:
+				defer func() { <-inFlight }()
+			default:
+				return nil, grpc.Errorf(codes.ResourceExhausted, "Server is handling the maximum of %d concurrent requests, retry later", maxConcurrent)
+			}
+		}
+
+		deadline := timeout
+		if d, ok := methodTimeouts[info.FullMethod]; ok {
+			deadline = d
+		}
+
+		if deadline > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, deadline)
+			defer cancel()
+		}
+
+		return handler(ctx, req)
+	}
+}
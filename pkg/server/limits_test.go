@@ -0,0 +1,93 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func TestLimitRequests_MaxConcurrent(t *testing.T) {
+	limiter := limitRequests(1, 0, nil)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test/Method"}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	blocking := func(ctx context.Context, req interface{}) (interface{}, error) {
+		close(started)
+		<-release
+		return "done", nil
+	}
+	done := make(chan error)
+	go func() {
+		_, err := limiter(context.Background(), nil, info, blocking)
+		done <- err
+	}()
+	<-started
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "done", nil
+	}
+	_, err := limiter(context.Background(), nil, info, handler)
+	assert.Equal(t, codes.ResourceExhausted, grpc.Code(err))
+
+	close(release)
+	require.NoError(t, <-done)
+
+	resp, err := limiter(context.Background(), nil, info, handler)
+	require.NoError(t, err)
+	assert.Equal(t, "done", resp)
+}
+
+func TestLimitRequests_Timeout(t *testing.T) {
+	limiter := limitRequests(0, time.Minute, nil)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test/Method"}
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+		return nil, nil
+	}
+	_, err := limiter(context.Background(), nil, info, handler)
+	require.NoError(t, err)
+
+	limiter = limitRequests(0, 0, nil)
+	handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+		return nil, nil
+	}
+	_, err = limiter(context.Background(), nil, info, handler)
+	require.NoError(t, err)
+}
+
+func TestLimitRequests_MethodTimeout(t *testing.T) {
+	limiter := limitRequests(0, time.Minute, map[string]time.Duration{
+		"/test/Slow": time.Hour,
+		"/test/None": 0,
+	})
+
+	expectDeadline := func(method string, timeout time.Duration) {
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			deadline, ok := ctx.Deadline()
+			if timeout == 0 {
+				assert.False(t, ok, method)
+				return nil, nil
+			}
+			require.True(t, ok, method)
+			assert.WithinDuration(t, time.Now().Add(timeout), deadline, time.Second, method)
+			return nil, nil
+		}
+		_, err := limiter(context.Background(), nil, info, handler)
+		require.NoError(t, err)
+	}
+
+	expectDeadline("/test/Method", time.Minute)
+	expectDeadline("/test/Slow", time.Hour)
+	expectDeadline("/test/None", 0)
+}
@@ -0,0 +1,20 @@
+package server
+
+import (
+	"github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// requestLogger returns a logger for a single API request. Its entries carry
+// a request ID, so that everything logged while serving the request can be
+// correlated, and the SPIFFE ID of the caller if it presented an SVID.
+func requestLogger(ctx context.Context, l logrus.FieldLogger) logrus.FieldLogger {
+	fields := logrus.Fields{"request_id": uuid.NewV4().String()}
+
+	if spiffeID, err := getSpiffeIDFromCtx(ctx); err == nil {
+		fields["caller_id"] = spiffeID
+	}
+
+	return l.WithFields(fields)
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/spiffe/go-spiffe/uri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+func TestRequestLogger(t *testing.T) {
+	l, hook := test.NewNullLogger()
+
+	requestLogger(context.Background(), l).Info("foo")
+	requestLogger(context.Background(), l).Info("bar")
+	require.Len(t, hook.Entries, 2)
+
+	first, second := hook.Entries[0].Data, hook.Entries[1].Data
+	assert.NotEmpty(t, first["request_id"])
+	assert.NotEqual(t, first["request_id"], second["request_id"])
+	assert.NotContains(t, first, "caller_id")
+
+	hook.Reset()
+	spiffeID := &url.URL{Scheme: "spiffe", Host: "example.org", Path: "/spiffe/node-id/foo"}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{
+			State: tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{createCertificate(t, spiffeID.String())},
+			},
+		},
+	})
+	requestLogger(ctx, l).Info("baz")
+	require.Len(t, hook.Entries, 1)
+	assert.Equal(t, logrus.Fields{
+		"request_id": hook.Entries[0].Data["request_id"],
+		"caller_id":  spiffeID.String(),
+	}, hook.Entries[0].Data)
+}
+
+func createCertificate(t *testing.T, spiffeID string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	uriSANs, err := uri.MarshalUriSANs([]string{spiffeID})
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtraExtensions: []pkix.Extension{{
+			Id:       uri.OidExtensionSubjectAltName,
+			Value:    uriSANs,
+			Critical: true,
+		}},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
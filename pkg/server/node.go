@@ -4,6 +4,7 @@ import (
 	"crypto/x509"
 	"errors"
 	"reflect"
+	"regexp"
 	"sort"
 	"time"
 
@@ -26,6 +27,8 @@ type nodeServer struct {
 	l               logrus.FieldLogger
 	catalog         catalog.Catalog
 	baseSpiffeIDTTL int32
+
+	spiffeIDPathPattern *regexp.Regexp
 }
 
 //FetchBaseSVID attests the node and gets the base node SVID.
@@ -33,49 +36,51 @@ func (s *nodeServer) FetchBaseSVID(
 	ctx context.Context, request *node.FetchBaseSVIDRequest) (
 	response *node.FetchBaseSVIDResponse, err error) {
 
+	log := requestLogger(ctx, s.l)
+
 	serverCA := s.catalog.CAs()[0]
 
 	baseSpiffeIDFromCSR, err := getSpiffeIDFromCSR(request.Csr)
 	if err != nil {
-		s.l.Error(err)
+		log.Error(err)
 		return response, errors.New("Error trying to get SpiffeId from CSR")
 	}
 
 	attestedBefore, err := s.isAttested(baseSpiffeIDFromCSR)
 	if err != nil {
-		s.l.Error(err)
+		log.Error(err)
 		return response, errors.New("Error trying to check if attested")
 	}
 
 	attestResponse, err := s.attest(request.AttestedData, attestedBefore)
 	if err != nil {
-		s.l.Error(err)
+		log.Error(err)
 		return response, errors.New("Error trying to attest")
 	}
 
 	err = s.validateAttestation(baseSpiffeIDFromCSR, attestResponse)
 	if err != nil {
-		s.l.Error(err)
+		log.Error(err)
 		return response, errors.New("Error trying to validate attestation")
 	}
 
 	signResponse, err := serverCA.SignCsr(&ca.SignCsrRequest{Csr: request.Csr})
 	if err != nil {
-		s.l.Error(err)
+		log.Error(err)
 		return response, errors.New("Error trying to sign CSR")
 	}
 
 	if attestedBefore {
 		err = s.updateAttestationEntry(signResponse.SignedCertificate, baseSpiffeIDFromCSR)
 		if err != nil {
-			s.l.Error(err)
+			log.Error(err)
 			return response, errors.New("Error trying to update attestation entry")
 		}
 
 	} else {
 		err = s.createAttestationEntry(signResponse.SignedCertificate, baseSpiffeIDFromCSR, request.AttestedData.Type)
 		if err != nil {
-			s.l.Error(err)
+			log.Error(err)
 			return response, errors.New("Error trying to create attestation entry")
 		}
 
@@ -83,14 +88,14 @@ func (s *nodeServer) FetchBaseSVID(
 
 	selectors, err := s.resolveSelectors(baseSpiffeIDFromCSR)
 	if err != nil {
-		s.l.Error(err)
+		log.Error(err)
 		return response, errors.New("Error trying to get selectors for baseSpiffeID")
 	}
 
 	response, err = s.getFetchBaseSVIDResponse(
 		baseSpiffeIDFromCSR, signResponse.SignedCertificate, selectors)
 	if err != nil {
-		s.l.Error(err)
+		log.Error(err)
 		return response, errors.New("Error trying to compose response")
 	}
 
@@ -104,27 +109,29 @@ func (s *nodeServer) FetchSVID(
 	ctx context.Context, request *node.FetchSVIDRequest) (
 	response *node.FetchSVIDResponse, err error) {
 
-	baseSpiffeID, err := s.getSpiffeIDFromCtx(ctx)
+	log := requestLogger(ctx, s.l)
+
+	baseSpiffeID, err := getSpiffeIDFromCtx(ctx)
 	if err != nil {
-		s.l.Error(err)
+		log.Error(err)
 		return response, errors.New("Error trying to get spiffeID from caller")
 	}
 
 	selectors, err := s.getStoredSelectors(baseSpiffeID)
 	if err != nil {
-		s.l.Error(err)
+		log.Error(err)
 		return response, errors.New("Error trying to get stored selectors")
 	}
 
 	regEntries, err := s.fetchRegistrationEntries(selectors, baseSpiffeID)
 	if err != nil {
-		s.l.Error(err)
+		log.Error(err)
 		return response, errors.New("Error trying to get registration entries")
 	}
 
-	svids, err := s.signCSRs(request.Csrs, regEntries)
+	svids, err := s.signCSRs(log, request.Csrs, regEntries)
 	if err != nil {
-		s.l.Error(err)
+		log.Error(err)
 		return response, errors.New("Error trying sign CSRs")
 	}
 
@@ -369,11 +376,14 @@ func (s *nodeServer) getFetchBaseSVIDResponse(
 	return &node.FetchBaseSVIDResponse{SvidUpdate: svidUpdate}, nil
 }
 
-func (s *nodeServer) getSpiffeIDFromCtx(ctx context.Context) (spiffeID string, err error) {
+func getSpiffeIDFromCtx(ctx context.Context) (spiffeID string, err error) {
 
-	ctxPeer, _ := peer.FromContext(ctx)
+	ctxPeer, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", errors.New("It was not posible to read a SVID from your request")
+	}
 	tlsInfo, ok := ctxPeer.AuthInfo.(credentials.TLSInfo)
-	if ok {
+	if ok && len(tlsInfo.State.PeerCertificates) > 0 {
 		spiffeID, err := uri.GetURINamesFromCertificate(tlsInfo.State.PeerCertificates[0])
 		if err != nil {
 			return "", err
@@ -383,7 +393,7 @@ func (s *nodeServer) getSpiffeIDFromCtx(ctx context.Context) (spiffeID string, e
 	return "", errors.New("It was not posible to read a SVID from your request")
 }
 
-func (s *nodeServer) signCSRs(
+func (s *nodeServer) signCSRs(log logrus.FieldLogger,
 	csrs [][]byte, regEntries []*common.RegistrationEntry) (
 	svids map[string]*node.Svid, err error) {
 
@@ -410,6 +420,14 @@ func (s *nodeServer) signCSRs(
 			return nil, err
 		}
 
+		//entries created before the path policy was put in place may not comply,
+		//skip them rather than failing the SVIDs of every other workload
+		err = checkSpiffeIDPath(s.spiffeIDPathPattern, spiffeID)
+		if err != nil {
+			log.Warnf("Not signing CSR: %s", err)
+			continue
+		}
+
 		//sign
 		signReq := &ca.SignCsrRequest{Csr: csr}
 		res, err := serverCA.SignCsr(signReq)
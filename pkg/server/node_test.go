@@ -1,15 +1,29 @@
 package server
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"regexp"
 	"testing"
 
-	//"github.com/golang/mock/gomock"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/go-spiffe/uri"
 	//pb "github.com/spiffe/spire/pkg/api/node"
-	//"github.com/spiffe/spire/pkg/common"
+	"github.com/spiffe/spire/pkg/server/catalog"
+	"github.com/spiffe/spire/proto/api/node"
+	"github.com/spiffe/spire/proto/common"
 	"github.com/spiffe/spire/proto/server/ca"
 	"github.com/spiffe/spire/proto/server/datastore"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
 	//"github.com/spiffe/spire/pkg/server/nodeattestor"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -213,3 +227,79 @@ func (suite *NodeServiceTestSuite) TestFetchSVID() {
 	suite.Assertions.Nil(err, "There should be no error.")
 }
 */
+
+// fakeCatalog only serves the plugins configured on it
+type fakeCatalog struct {
+	catalog.Catalog
+	cas           []ca.ControlPlaneCa
+	dataStores    []datastore.DataStore
+	nodeAttestors []nodeattestor.NodeAttestor
+}
+
+func (c *fakeCatalog) CAs() []ca.ControlPlaneCa {
+	return c.cas
+}
+
+func (c *fakeCatalog) DataStores() []datastore.DataStore {
+	return c.dataStores
+}
+
+func (c *fakeCatalog) NodeAttestors() []nodeattestor.NodeAttestor {
+	return c.nodeAttestors
+}
+
+func TestSignCSRs_SkipsNonCompliantPath(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const prodSpiffeID = "spiffe://example.org/prod/billing"
+	const legacySpiffeID = "spiffe://example.org/legacy"
+
+	prodCsr := createCSR(t, prodSpiffeID)
+	legacyCsr := createCSR(t, legacySpiffeID)
+
+	mockServerCA := ca.NewMockControlPlaneCa(mockCtrl)
+	mockServerCA.EXPECT().
+		SignCsr(&ca.SignCsrRequest{Csr: prodCsr}).
+		Return(&ca.SignCsrResponse{SignedCertificate: []byte("prod cert")}, nil)
+
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	server := &nodeServer{
+		l:                   log,
+		catalog:             &fakeCatalog{cas: []ca.ControlPlaneCa{mockServerCA}},
+		spiffeIDPathPattern: regexp.MustCompile(`^/prod/.*$`),
+	}
+
+	regEntries := []*common.RegistrationEntry{
+		{SpiffeId: prodSpiffeID, Ttl: 1111},
+		{SpiffeId: legacySpiffeID, Ttl: 2222},
+	}
+
+	svids, err := server.signCSRs(log, [][]byte{legacyCsr, prodCsr}, regEntries)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]*node.Svid{
+		prodSpiffeID: {SvidCert: []byte("prod cert"), Ttl: 1111},
+	}, svids)
+}
+
+func createCSR(t *testing.T, spiffeID string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	uriSANs, err := uri.MarshalUriSANs([]string{spiffeID})
+	require.NoError(t, err)
+
+	template := &x509.CertificateRequest{
+		ExtraExtensions: []pkix.Extension{{
+			Id:       uri.OidExtensionSubjectAltName,
+			Value:    uriSANs,
+			Critical: true,
+		}},
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	require.NoError(t, err)
+
+	return csr
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+// checkSpiffeIDPath returns an error if the path of the given SPIFFE ID
+// does not match the configured pattern. A nil pattern allows any path.
+func checkSpiffeIDPath(pattern *regexp.Regexp, spiffeID string) error {
+	if pattern == nil {
+		return nil
+	}
+
+	id, err := url.Parse(spiffeID)
+	if err != nil {
+		return err
+	}
+
+	if !pattern.MatchString(id.Path) {
+		return fmt.Errorf("SPIFFE ID %s does not match the path policy %s", spiffeID, pattern)
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package server
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckSpiffeIDPath(t *testing.T) {
+	assert.NoError(t, checkSpiffeIDPath(nil, "spiffe://example.org/anything"))
+
+	pattern := regexp.MustCompile(`^/(prod|staging)/[a-z-]+$`)
+	assert.NoError(t, checkSpiffeIDPath(pattern, "spiffe://example.org/prod/billing"))
+	assert.NoError(t, checkSpiffeIDPath(pattern, "spiffe://example.org/staging/web-front"))
+	assert.Error(t, checkSpiffeIDPath(pattern, "spiffe://example.org/dev/billing"))
+	assert.Error(t, checkSpiffeIDPath(pattern, "spiffe://example.org/prod/billing/extra"))
+	assert.Error(t, checkSpiffeIDPath(pattern, "spiffe://example.org"))
+	assert.Error(t, checkSpiffeIDPath(pattern, "%zz"))
+}
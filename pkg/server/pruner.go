@@ -0,0 +1,64 @@
+package server
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/spiffe/spire/pkg/server/catalog"
+	"github.com/spiffe/spire/proto/server/datastore"
+)
+
+// joinTokenAttestationType is the attestation type of nodes attested with a
+// join token. The join token attestor rejects a token only when its node was
+// attested before, so these nodes are never pruned: deleting one would let
+// its token be used again once the server restarts.
+const joinTokenAttestationType = "join_token"
+
+// attestedNodePruner deletes attested nodes whose base SVID expired more
+// than the retention period ago. Such agents must attest again anyway, and
+// without pruning the table grows with every node that ever joined.
+type attestedNodePruner struct {
+	l         logrus.FieldLogger
+	catalog   catalog.Catalog
+	retention time.Duration
+}
+
+// prune deletes the attested nodes eligible for pruning at the given time
+// and returns how many were deleted. Nodes which cannot be deleted are
+// logged and left for the next run.
+func (p *attestedNodePruner) prune(now time.Time) (int, error) {
+	dataStore := p.catalog.DataStores()[0]
+
+	resp, err := dataStore.FetchStaleNodeEntries(&datastore.FetchStaleNodeEntriesRequest{})
+	if err != nil {
+		return 0, err
+	}
+
+	var pruned int
+	for _, entry := range resp.AttestedNodeEntryList {
+		if entry.AttestedDataType == joinTokenAttestationType {
+			continue
+		}
+
+		expiresAt, err := time.Parse(datastore.TimeFormat, entry.CertExpirationDate)
+		if err != nil {
+			p.l.Warnf("Not pruning attested node %s: %s", entry.BaseSpiffeId, err)
+			continue
+		}
+
+		if now.Before(expiresAt.Add(p.retention)) {
+			continue
+		}
+
+		_, err = dataStore.DeleteAttestedNodeEntry(&datastore.DeleteAttestedNodeEntryRequest{BaseSpiffeId: entry.BaseSpiffeId})
+		if err != nil {
+			p.l.Warnf("Could not prune attested node %s: %s", entry.BaseSpiffeId, err)
+			continue
+		}
+
+		pruned++
+	}
+
+	return pruned, nil
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/spiffe/spire/proto/api/node"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/server/ca"
+	"github.com/spiffe/spire/proto/server/datastore"
+	"github.com/spiffe/spire/proto/server/nodeattestor"
+)
+
+func TestAttestedNodePruner(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	now := time.Now()
+	expiredAt := func(ago time.Duration) string {
+		return now.Add(-ago).Format(datastore.TimeFormat)
+	}
+
+	mockDataStore := datastore.NewMockDataStore(mockCtrl)
+	mockDataStore.EXPECT().
+		FetchStaleNodeEntries(&datastore.FetchStaleNodeEntriesRequest{}).
+		Return(&datastore.FetchStaleNodeEntriesResponse{
+			AttestedNodeEntryList: []*datastore.AttestedNodeEntry{
+				{BaseSpiffeId: "spiffe://example.org/old", CertExpirationDate: expiredAt(48 * time.Hour)},
+				{BaseSpiffeId: "spiffe://example.org/recent", CertExpirationDate: expiredAt(time.Hour)},
+				{BaseSpiffeId: "spiffe://example.org/broken", CertExpirationDate: "foo"},
+				{BaseSpiffeId: "spiffe://example.org/locked", CertExpirationDate: expiredAt(48 * time.Hour)},
+			},
+		}, nil)
+	mockDataStore.EXPECT().
+		DeleteAttestedNodeEntry(&datastore.DeleteAttestedNodeEntryRequest{BaseSpiffeId: "spiffe://example.org/old"}).
+		Return(&datastore.DeleteAttestedNodeEntryResponse{}, nil)
+	mockDataStore.EXPECT().
+		DeleteAttestedNodeEntry(&datastore.DeleteAttestedNodeEntryRequest{BaseSpiffeId: "spiffe://example.org/locked"}).
+		Return(nil, errors.New("database is locked"))
+
+	l, _ := test.NewNullLogger()
+	pruner := &attestedNodePruner{
+		l:         l,
+		catalog:   &fakeCatalog{dataStores: []datastore.DataStore{mockDataStore}},
+		retention: 24 * time.Hour,
+	}
+
+	pruned, err := pruner.prune(now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, pruned)
+}
+
+func TestAttestedNodePruner_FetchError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockDataStore := datastore.NewMockDataStore(mockCtrl)
+	mockDataStore.EXPECT().
+		FetchStaleNodeEntries(&datastore.FetchStaleNodeEntriesRequest{}).
+		Return(nil, errors.New("foo"))
+
+	l, _ := test.NewNullLogger()
+	pruner := &attestedNodePruner{
+		l:       l,
+		catalog: &fakeCatalog{dataStores: []datastore.DataStore{mockDataStore}},
+	}
+
+	_, err := pruner.prune(time.Now())
+	assert.Error(t, err)
+}
+
+func TestAttestedNodePruner_KeepsJoinTokenNodes(t *testing.T) {
+	const tokenSpiffeID = "spiffe://example.org/spiffe/node-id/foo"
+	const otherSpiffeID = "spiffe://example.org/spiffe/node-id/bar"
+
+	expired := time.Now().Add(-48 * time.Hour).Format(datastore.TimeFormat)
+	dataStore := &fakeNodeDataStore{nodes: map[string]*datastore.AttestedNodeEntry{
+		tokenSpiffeID: {BaseSpiffeId: tokenSpiffeID, AttestedDataType: "join_token", CertExpirationDate: expired},
+		otherSpiffeID: {BaseSpiffeId: otherSpiffeID, AttestedDataType: "aws_iid", CertExpirationDate: expired},
+	}}
+
+	l, _ := test.NewNullLogger()
+	pruner := &attestedNodePruner{
+		l:         l,
+		catalog:   &fakeCatalog{dataStores: []datastore.DataStore{dataStore}},
+		retention: 24 * time.Hour,
+	}
+
+	pruned, err := pruner.prune(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, pruned)
+	assert.Contains(t, dataStore.nodes, tokenSpiffeID)
+
+	// Restart the server. The attested nodes outlive it, but the join token
+	// attestor forgets which tokens were used.
+	attestor := &fakeJoinTokenAttestor{tokens: map[string]bool{"foo": true}}
+	server := &nodeServer{
+		l: l,
+		catalog: &fakeCatalog{
+			cas:           []ca.ControlPlaneCa{ca.NewMockControlPlaneCa(gomock.NewController(t))},
+			dataStores:    []datastore.DataStore{dataStore},
+			nodeAttestors: []nodeattestor.NodeAttestor{attestor},
+		},
+	}
+
+	_, err = server.FetchBaseSVID(context.Background(), &node.FetchBaseSVIDRequest{
+		Csr:          createCSR(t, tokenSpiffeID),
+		AttestedData: &common.AttestedData{Type: "join_token", Data: []byte("foo")},
+	})
+	assert.Error(t, err)
+	assert.True(t, attestor.attestedBefore)
+}
+
+func TestStartPruning(t *testing.T) {
+	dataStore := &fakeNodeDataStore{fetched: make(chan struct{}, 1)}
+
+	l, _ := test.NewNullLogger()
+	server := &Server{
+		Catalog: &fakeCatalog{dataStores: []datastore.DataStore{dataStore}},
+		Config: &Config{
+			Log:           l,
+			PruneInterval: time.Millisecond,
+		},
+	}
+
+	stop := server.startPruning()
+	select {
+	case <-dataStore.fetched:
+	case <-time.After(time.Second):
+		t.Fatal("Attested nodes were not pruned")
+	}
+	stop()
+}
+
+// fakeNodeDataStore keeps attested nodes in a map which outlives the
+// servers using it, like a database file does
+type fakeNodeDataStore struct {
+	datastore.DataStore
+	nodes map[string]*datastore.AttestedNodeEntry
+
+	// fetched is signalled, if set, when stale nodes are fetched
+	fetched chan struct{}
+}
+
+func (d *fakeNodeDataStore) FetchAttestedNodeEntry(req *datastore.FetchAttestedNodeEntryRequest) (*datastore.FetchAttestedNodeEntryResponse, error) {
+	return &datastore.FetchAttestedNodeEntryResponse{AttestedNodeEntry: d.nodes[req.BaseSpiffeId]}, nil
+}
+
+func (d *fakeNodeDataStore) FetchStaleNodeEntries(*datastore.FetchStaleNodeEntriesRequest) (*datastore.FetchStaleNodeEntriesResponse, error) {
+	if d.fetched != nil {
+		select {
+		case d.fetched <- struct{}{}:
+		default:
+		}
+	}
+
+	resp := &datastore.FetchStaleNodeEntriesResponse{}
+	for _, entry := range d.nodes {
+		resp.AttestedNodeEntryList = append(resp.AttestedNodeEntryList, entry)
+	}
+	return resp, nil
+}
+
+func (d *fakeNodeDataStore) DeleteAttestedNodeEntry(req *datastore.DeleteAttestedNodeEntryRequest) (*datastore.DeleteAttestedNodeEntryResponse, error) {
+	entry := d.nodes[req.BaseSpiffeId]
+	delete(d.nodes, req.BaseSpiffeId)
+	return &datastore.DeleteAttestedNodeEntryResponse{AttestedNodeEntry: entry}, nil
+}
+
+// fakeJoinTokenAttestor accepts each configured token, unless the node was
+// attested before, as the join token plugin does
+type fakeJoinTokenAttestor struct {
+	nodeattestor.NodeAttestor
+	tokens         map[string]bool
+	attestedBefore bool
+}
+
+func (a *fakeJoinTokenAttestor) Attest(req *nodeattestor.AttestRequest) (*nodeattestor.AttestResponse, error) {
+	token := string(req.AttestedData.Data)
+
+	a.attestedBefore = req.AttestedBefore
+	if req.AttestedBefore {
+		return &nodeattestor.AttestResponse{Valid: false}, fmt.Errorf("Join token %s has been used and is no longer valid", token)
+	}
+	if !a.tokens[token] {
+		return &nodeattestor.AttestResponse{Valid: false}, errors.New("Unknown or expired join token")
+	}
+
+	return &nodeattestor.AttestResponse{
+		Valid:        true,
+		BaseSPIFFEID: "spiffe://example.org/spiffe/node-id/" + token,
+	}, nil
+}
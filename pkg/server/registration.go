@@ -2,6 +2,7 @@ package server
 
 import (
 	"errors"
+	"regexp"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spiffe/spire/pkg/server/catalog"
@@ -9,6 +10,8 @@ import (
 	"github.com/spiffe/spire/proto/common"
 	"github.com/spiffe/spire/proto/server/datastore"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 //Service is used to register SPIFFE IDs, and the attestation logic that should
@@ -16,6 +19,8 @@ import (
 type registrationServer struct {
 	l       logrus.FieldLogger
 	catalog catalog.Catalog
+
+	spiffeIDPathPattern *regexp.Regexp
 }
 
 //Creates an entry in the Registration table,
@@ -24,13 +29,21 @@ func (s *registrationServer) CreateEntry(
 	ctx context.Context, request *common.RegistrationEntry) (
 	response *registration.RegistrationEntryID, err error) {
 
+	log := requestLogger(ctx, s.l)
+
+	err = checkSpiffeIDPath(s.spiffeIDPathPattern, request.SpiffeId)
+	if err != nil {
+		log.Error(err)
+		return response, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	dataStore := s.catalog.DataStores()[0]
 	createResponse, err := dataStore.CreateRegistrationEntry(
 		&datastore.CreateRegistrationEntryRequest{RegisteredEntry: request},
 	)
 
 	if err != nil {
-		s.l.Error(err)
+		log.Error(err)
 		return response, errors.New("Error trying to create entry")
 	}
 
@@ -49,12 +62,14 @@ func (s *registrationServer) FetchEntry(
 	ctx context.Context, request *registration.RegistrationEntryID) (
 	response *common.RegistrationEntry, err error) {
 
+	log := requestLogger(ctx, s.l)
+
 	dataStore := s.catalog.DataStores()[0]
 	fetchResponse, err := dataStore.FetchRegistrationEntry(
 		&datastore.FetchRegistrationEntryRequest{RegisteredEntryId: request.Id},
 	)
 	if err != nil {
-		s.l.Error(err)
+		log.Error(err)
 		return response, errors.New("Error trying to fetch entry")
 	}
 	return fetchResponse.RegisteredEntry, nil
@@ -72,12 +87,14 @@ func (s *registrationServer) ListByParentID(
 	ctx context.Context, request *registration.ParentID) (
 	response *common.RegistrationEntries, err error) {
 
+	log := requestLogger(ctx, s.l)
+
 	dataStore := s.catalog.DataStores()[0]
 	listResponse, err := dataStore.ListParentIDEntries(
 		&datastore.ListParentIDEntriesRequest{ParentId: request.Id},
 	)
 	if err != nil {
-		s.l.Error(err)
+		log.Error(err)
 		return response, errors.New("Error trying to list entries by parent ID")
 	}
 
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"io/ioutil"
+	"regexp"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/spiffe/spire/proto/api/registration"
+	"github.com/spiffe/spire/proto/common"
+	"github.com/spiffe/spire/proto/server/datastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateEntry_SpiffeIDPathPolicy(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	prodEntry := &common.RegistrationEntry{SpiffeId: "spiffe://example.org/prod/billing"}
+	legacyEntry := &common.RegistrationEntry{SpiffeId: "spiffe://example.org/legacy"}
+
+	// Only the compliant entry makes it to the datastore
+	mockDataStore := datastore.NewMockDataStore(mockCtrl)
+	mockDataStore.EXPECT().
+		CreateRegistrationEntry(&datastore.CreateRegistrationEntryRequest{RegisteredEntry: prodEntry}).
+		Return(&datastore.CreateRegistrationEntryResponse{RegisteredEntryId: "prod"}, nil)
+
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	server := &registrationServer{
+		l:                   log,
+		catalog:             &fakeCatalog{dataStores: []datastore.DataStore{mockDataStore}},
+		spiffeIDPathPattern: regexp.MustCompile(`^/prod/.*$`),
+	}
+
+	id, err := server.CreateEntry(context.Background(), prodEntry)
+	require.NoError(t, err)
+	assert.Equal(t, &registration.RegistrationEntryID{Id: "prod"}, id)
+
+	id, err = server.CreateEntry(context.Background(), legacyEntry)
+	assert.Nil(t, id)
+	require.Error(t, err)
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Contains(t, st.Message(), "spiffe://example.org/legacy")
+}
@@ -13,10 +13,13 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"regexp"
+	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
 	"github.com/sirupsen/logrus"
 	"github.com/spiffe/go-spiffe/uri"
+	"github.com/spiffe/spire/pkg/common/systemd"
 	"github.com/spiffe/spire/pkg/server/catalog"
 	spinode "github.com/spiffe/spire/proto/api/node"
 	spiregistration "github.com/spiffe/spire/proto/api/registration"
@@ -50,6 +53,26 @@ type Config struct {
 
 	// Trust domain
 	TrustDomain url.URL
+
+	// Maximum number of API requests handled at once, zero for no limit
+	MaxConcurrentRequests int
+
+	// Deadline applied to each API request, zero for none
+	RequestTimeout time.Duration
+
+	// Deadlines overriding RequestTimeout for specific API methods, keyed
+	// by full method name, e.g. /spire.api.node.Node/FetchSVID
+	MethodRequestTimeouts map[string]time.Duration
+
+	// Pattern that the paths of registered SPIFFE IDs must match,
+	// nil to allow any path
+	SpiffeIDPathPattern *regexp.Regexp
+
+	// How often expired attested nodes are pruned, zero to never prune
+	PruneInterval time.Duration
+
+	// How long attested nodes are kept after their base SVID expires
+	AttestedNodeRetention time.Duration
 }
 
 type Server struct {
@@ -85,20 +108,89 @@ func (server *Server) Run() error {
 		return err
 	}
 
+	watchdog, stopWatchdog, err := systemd.WatchdogTicker()
+	if err != nil {
+		server.Config.Log.Warnf("Not sending systemd watchdog keep-alives: %s", err)
+	}
+	defer stopWatchdog()
+
+	stopPruning := server.startPruning()
+	defer stopPruning()
+
+	server.notifySystemd(systemd.Ready)
+
 	// Main event loop
 	server.Config.Log.Info("SPIRE Server is now running")
 
 	for {
 		select {
 		case err = <-server.Config.ErrorCh:
+			server.notifySystemd(systemd.Stopping)
 			return err
 		case <-server.Config.ShutdownCh:
+			server.notifySystemd(systemd.Stopping)
 			server.grpcServer.GracefulStop()
 			return <-server.Config.ErrorCh
+		case <-watchdog:
+			server.notifySystemd(systemd.Watchdog)
 		}
 	}
 }
 
+// startPruning prunes expired attested nodes in its own goroutine, so that
+// a slow prune does not hold up the main event loop. It returns a function
+// which stops pruning, waiting for a prune in progress to finish.
+func (server *Server) startPruning() func() {
+	if server.Config.PruneInterval <= 0 {
+		return func() {}
+	}
+
+	ticker := time.NewTicker(server.Config.PruneInterval)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-ticker.C:
+				server.pruneAttestedNodes()
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ticker.Stop()
+		close(stop)
+		<-done
+	}
+}
+
+func (server *Server) pruneAttestedNodes() {
+	pruner := &attestedNodePruner{
+		l:         server.Config.Log,
+		catalog:   server.Catalog,
+		retention: server.Config.AttestedNodeRetention,
+	}
+
+	pruned, err := pruner.prune(time.Now())
+	if err != nil {
+		server.Config.Log.Warnf("Could not prune attested nodes: %s", err)
+		return
+	}
+
+	server.Config.Log.WithField("pruned", pruned).Info("Pruned expired attested nodes")
+}
+
+func (server *Server) notifySystemd(state string) {
+	err := systemd.Notify(state)
+	if err != nil {
+		server.Config.Log.Warnf("Unable to notify systemd of state %s: %s", state, err)
+	}
+}
+
 func (server *Server) initPlugins() error {
 	config := &catalog.Config{
 		ConfigDir: server.Config.PluginDir,
@@ -132,16 +224,20 @@ func (server *Server) initEndpoints() error {
 
 	server.Config.Log.Info("Starting the Registration API")
 	rs := &registrationServer{
-		l:       server.Config.Log,
+		l:       server.Config.Log.WithField("subsystem_name", "registration_api"),
 		catalog: server.Catalog,
+
+		spiffeIDPathPattern: server.Config.SpiffeIDPathPattern,
 	}
 	spiregistration.RegisterRegistrationServer(server.grpcServer, rs)
 
 	server.Config.Log.Info("Starting the Node API")
 	ns := &nodeServer{
-		l:               server.Config.Log,
+		l:               server.Config.Log.WithField("subsystem_name", "node_api"),
 		catalog:         server.Catalog,
 		baseSpiffeIDTTL: server.Config.BaseSpiffeIDTTL,
+
+		spiffeIDPathPattern: server.Config.SpiffeIDPathPattern,
 	}
 	spinode.RegisterNodeServer(server.grpcServer, ns)
 
@@ -151,6 +247,15 @@ func (server *Server) initEndpoints() error {
 		return fmt.Errorf("Error creating GRPC listener: %s", err)
 	}
 
+	// Bind the HTTP gateway here as well, so that both endpoints are
+	// accepting connections by the time systemd is told we are ready
+	server.Config.Log.Info(server.Config.BindHTTPAddress.String())
+	httpListener, err := net.Listen(server.Config.BindHTTPAddress.Network(), server.Config.BindHTTPAddress.String())
+	if err != nil {
+		listener.Close()
+		return fmt.Errorf("Error creating HTTP listener: %s", err)
+	}
+
 	//gRPC
 	go func() {
 		server.Config.ErrorCh <- server.grpcServer.Serve(listener)
@@ -173,11 +278,11 @@ func (server *Server) initEndpoints() error {
 
 		err := spiregistration.RegisterRegistrationHandlerFromEndpoint(ctx, mux, server.Config.BindAddress.String(), opts)
 		if err != nil {
+			httpListener.Close()
 			server.Config.ErrorCh <- err
 			return
 		}
-		server.Config.Log.Info(server.Config.BindHTTPAddress.String())
-		server.Config.ErrorCh <- http.ListenAndServe(server.Config.BindHTTPAddress.String(), mux)
+		server.Config.ErrorCh <- http.Serve(httpListener, mux)
 	}()
 
 	return nil
@@ -275,5 +380,6 @@ func (server *Server) getGRPCServer() (*grpc.Server, error) {
 		ClientAuth:   tls.RequestClientCert,
 	}
 
-	return grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig))), nil
+	limiter := limitRequests(server.Config.MaxConcurrentRequests, server.Config.RequestTimeout, server.Config.MethodRequestTimeouts)
+	return grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)), grpc.UnaryInterceptor(limiter)), nil
 }
@@ -3,7 +3,11 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/hashicorp/go-plugin"
 	"github.com/shirou/gopsutil/process"
@@ -17,6 +21,10 @@ type UnixPlugin struct{}
 
 const selectorType string = "unix"
 
+// procDir is where procfs is mounted. It is a var so tests can point it
+// at a fake tree.
+var procDir = "/proc"
+
 func (UnixPlugin) Attest(req *workloadattestor.AttestRequest) (*workloadattestor.AttestResponse, error) {
 	log.Printf("Attesting PID: %v", req.Pid)
 
@@ -61,10 +69,46 @@ func (UnixPlugin) Attest(req *workloadattestor.AttestRequest) (*workloadattestor
 		return &resp, errors.New(fmt.Sprintf("Unable to get effective GID for PID: %v", req.Pid))
 	}
 
+	resp.Selectors = append(resp.Selectors, macSelectors(req.Pid)...)
+
 	log.Printf("Selectors found: %v", resp.Selectors)
 	return &resp, nil
 }
 
+// macSelectors returns selectors for the SELinux label or AppArmor profile
+// of the given process. Hosts without an LSM exposing a process context
+// simply yield no selectors.
+func macSelectors(pid int32) []*common.Selector {
+	path := filepath.Join(procDir, strconv.Itoa(int(pid)), "attr", "current")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	label := strings.TrimRight(string(data), "\x00\n")
+	if label == "" {
+		return nil
+	}
+
+	// AppArmor reports "unconfined" or "<profile> (<mode>)"
+	if label == "unconfined" {
+		return []*common.Selector{{Type: selectorType, Value: "apparmor:unconfined"}}
+	}
+	if i := strings.LastIndex(label, " ("); i > 0 && strings.HasSuffix(label, ")") {
+		return []*common.Selector{{Type: selectorType, Value: "apparmor:" + label[:i]}}
+	}
+
+	// SELinux reports "user:role:type[:level]"
+	parts := strings.SplitN(label, ":", 4)
+	if len(parts) < 3 {
+		return nil
+	}
+	return []*common.Selector{
+		{Type: selectorType, Value: "selinux:" + label},
+		{Type: selectorType, Value: "selinux_type:" + parts[2]},
+	}
+}
+
 func (UnixPlugin) Configure(*spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
 	return &spi.ConfigureResponse{}, nil
 }
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/spiffe/spire/proto/agent/workloadattestor"
+	"github.com/spiffe/spire/proto/common"
 	spi "github.com/spiffe/spire/proto/common/plugin"
 )
 
@@ -27,6 +30,41 @@ func TestUnix_AttestInvalidPID(t *testing.T) {
 	require.Empty(t, resp.Selectors)
 }
 
+func TestUnix_macSelectors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spire-unix-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	oldProcDir := procDir
+	procDir = dir
+	defer func() { procDir = oldProcDir }()
+
+	attrDir := filepath.Join(dir, "1", "attr")
+	require.NoError(t, os.MkdirAll(attrDir, 0755))
+
+	var tests = []struct {
+		label     string
+		selectors []*common.Selector
+	}{
+		{"", nil},
+		{"garbage", nil},
+		{"unconfined\n", []*common.Selector{{Type: "unix", Value: "apparmor:unconfined"}}},
+		{"/usr/bin/foo (enforce)\n", []*common.Selector{{Type: "unix", Value: "apparmor:/usr/bin/foo"}}},
+		{"system_u:system_r:httpd_t:s0:c1,c2\x00", []*common.Selector{
+			{Type: "unix", Value: "selinux:system_u:system_r:httpd_t:s0:c1,c2"},
+			{Type: "unix", Value: "selinux_type:httpd_t"},
+		}},
+	}
+
+	for _, tt := range tests {
+		err := ioutil.WriteFile(filepath.Join(attrDir, "current"), []byte(tt.label), 0644)
+		require.NoError(t, err)
+		assert.Equal(t, tt.selectors, macSelectors(1), tt.label)
+	}
+
+	assert.Empty(t, macSelectors(2))
+}
+
 func TestUnix_Configure(t *testing.T) {
 	var plugin UnixPlugin
 	data, e := plugin.Configure(&spi.ConfigureRequest{})
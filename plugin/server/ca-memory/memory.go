@@ -45,15 +45,18 @@ type configuration struct {
 	KeySize     int               `hcl:"key_size" json:"key_size"`
 	TTL         string            `hcl:"ttl" json:"ttl"`
 	CertSubject certSubjectConfig `hcl:"cert_subject" json:"cert_subject"`
+	UseRSAPSS   bool              `hcl:"use_rsa_pss" json:"use_rsa_pss"`
+	Backdate    string            `hcl:"backdate" json:"backdate"`
 }
 
 type memoryPlugin struct {
 	config *configuration
 
-	key    *rsa.PrivateKey
-	newKey *rsa.PrivateKey
-	cert   *x509.Certificate
-	serial int64
+	key      *rsa.PrivateKey
+	newKey   *rsa.PrivateKey
+	cert     *x509.Certificate
+	serial   int64
+	backdate time.Duration
 
 	mtx *sync.RWMutex
 }
@@ -76,6 +79,12 @@ func (m *memoryPlugin) Configure(req *spi.ConfigureRequest) (*spi.ConfigureRespo
 		return resp, err
 	}
 
+	backdate, err := pkg.ParseBackdate(config.Backdate, config.TTL)
+	if err != nil {
+		resp.ErrorList = []string{err.Error()}
+		return resp, err
+	}
+
 	// Set local vars from config struct
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
@@ -84,6 +93,9 @@ func (m *memoryPlugin) Configure(req *spi.ConfigureRequest) (*spi.ConfigureRespo
 	m.config.TTL = config.TTL
 	m.config.KeySize = config.KeySize
 	m.config.CertSubject = config.CertSubject
+	m.config.UseRSAPSS = config.UseRSAPSS
+	m.config.Backdate = config.Backdate
+	m.backdate = backdate
 
 	return resp, nil
 }
@@ -121,13 +133,14 @@ func (m *memoryPlugin) SignCsr(request *ca.SignCsrRequest) (*ca.SignCsrResponse,
 		Subject:         csr.Subject,
 		Issuer:          csr.Subject,
 		SerialNumber:    big.NewInt(serial),
-		NotBefore:       now,
+		NotBefore:       now.Add(-m.backdate),
 		NotAfter:        now.Add(expiry),
 		KeyUsage: x509.KeyUsageKeyEncipherment |
 			x509.KeyUsageKeyAgreement |
 			x509.KeyUsageDigitalSignature,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
 		BasicConstraintsValid: true,
+		SignatureAlgorithm:    m.signatureAlgorithm(),
 	}
 
 	signedCertificate, err := x509.CreateCertificate(rand.Reader,
@@ -171,7 +184,7 @@ func (m *memoryPlugin) GenerateCsr(*ca.GenerateCsrRequest) (*ca.GenerateCsrRespo
 
 	template := x509.CertificateRequest{
 		Subject:            subject,
-		SignatureAlgorithm: x509.SHA256WithRSA,
+		SignatureAlgorithm: m.signatureAlgorithm(),
 		ExtraExtensions: []pkix.Extension{
 			{
 				Id:       uri.OidExtensionSubjectAltName,
@@ -287,6 +300,15 @@ func (m *memoryPlugin) LoadCertificate(request *ca.LoadCertificateRequest) (resp
 	return &ca.LoadCertificateResponse{}, nil
 }
 
+// signatureAlgorithm returns the algorithm used to sign with the CA key,
+// which is RSASSA-PSS if enabled in the config and PKCS#1 v1.5 otherwise
+func (m *memoryPlugin) signatureAlgorithm() x509.SignatureAlgorithm {
+	if m.config.UseRSAPSS {
+		return x509.SHA256WithRSAPSS
+	}
+	return x509.SHA256WithRSA
+}
+
 func NewWithDefault() (m ca.ControlPlaneCa, err error) {
 	config := configuration{
 		TrustDomain: "localhost",
@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/spiffe/go-spiffe/uri"
 	"github.com/stretchr/testify/assert"
@@ -73,6 +74,25 @@ func TestMemory_ConfigureDecodeObjectError(t *testing.T) {
 	assert.Equal(t, resp.GetErrorList(), expectedErrorList)
 }
 
+func TestMemory_ConfigureInvalidBackdate(t *testing.T) {
+	config := `{"trust_domain":"example.com", "ttl":"1h", "key_size":2048, "backdate":"2h"}`
+	pluginConfig := &spi.ConfigureRequest{
+		Configuration: config,
+	}
+
+	m := &memoryPlugin{
+		mtx: &sync.RWMutex{},
+	}
+
+	resp, err := m.Configure(pluginConfig)
+	expectedError := "Backdate 2h must be shorter than the TTL 1h"
+	expectedErrorList := []string{expectedError}
+
+	assert.Equal(t, err.Error(), expectedError)
+	assert.Equal(t, resp.GetErrorList(), expectedErrorList)
+	assert.Nil(t, m.config)
+}
+
 func TestMemory_GetPluginInfo(t *testing.T) {
 	m, err := NewWithDefault()
 	require.NoError(t, err)
@@ -205,6 +225,75 @@ func TestMemory_SignCsr(t *testing.T) {
 	assert.NotEmpty(t, wcert)
 }
 
+func TestMemory_SignCsrRSAPSS(t *testing.T) {
+	m := populateCert(t)
+
+	config := configuration{
+		TrustDomain: "localhost",
+		KeySize:     2048,
+		TTL:         "1h",
+		CertSubject: certSubjectConfig{
+			Country:      []string{"US"},
+			Organization: []string{"SPIFFE"},
+			CommonName:   "",
+		},
+		UseRSAPSS: true,
+	}
+
+	pluginConfig, err := populateConfigPlugin(config)
+	require.NoError(t, err)
+	_, err = m.Configure(pluginConfig)
+	require.NoError(t, err)
+
+	wcsr := createWorkloadCSR(t, "spiffe://localhost")
+
+	resp, err := m.SignCsr(&ca.SignCsrRequest{Csr: wcsr})
+	require.NoError(t, err)
+
+	wcert, err := x509.ParseCertificate(resp.SignedCertificate)
+	require.NoError(t, err)
+	assert.Equal(t, x509.SHA256WithRSAPSS, wcert.SignatureAlgorithm)
+
+	generateCsrResp, err := m.GenerateCsr(&ca.GenerateCsrRequest{})
+	require.NoError(t, err)
+
+	csr, err := x509.ParseCertificateRequest(generateCsrResp.Csr)
+	require.NoError(t, err)
+	assert.Equal(t, x509.SHA256WithRSAPSS, csr.SignatureAlgorithm)
+}
+
+func TestMemory_SignCsrBackdate(t *testing.T) {
+	m := populateCert(t)
+
+	config := configuration{
+		TrustDomain: "localhost",
+		KeySize:     2048,
+		TTL:         "1h",
+		CertSubject: certSubjectConfig{
+			Country:      []string{"US"},
+			Organization: []string{"SPIFFE"},
+			CommonName:   "",
+		},
+		Backdate: "1m",
+	}
+
+	pluginConfig, err := populateConfigPlugin(config)
+	require.NoError(t, err)
+	_, err = m.Configure(pluginConfig)
+	require.NoError(t, err)
+
+	wcsr := createWorkloadCSR(t, "spiffe://localhost")
+
+	now := time.Now()
+	resp, err := m.SignCsr(&ca.SignCsrRequest{Csr: wcsr})
+	require.NoError(t, err)
+
+	wcert, err := x509.ParseCertificate(resp.SignedCertificate)
+	require.NoError(t, err)
+	assert.True(t, wcert.NotBefore.Before(now.Add(-59*time.Second)))
+	assert.True(t, wcert.NotAfter.After(now.Add(59*time.Minute)))
+}
+
 func TestMemory_SignCsrNoCert(t *testing.T) {
 	m, err := NewWithDefault()
 	require.NoError(t, err)
@@ -0,0 +1,6 @@
+CREATE INDEX idx_selectors_type_value
+  ON selectors(type, value);
+
+CREATE INDEX idx_registered_entries_parent_id
+  ON registered_entries(parent_id)
+  WHERE deleted_at IS NULL;
@@ -1,6 +1,7 @@
 // Code generated by go-bindata.
 // sources:
 // _migrations/000-initial.up.sql
+// _migrations/001-selector-indexes.up.sql
 // DO NOT EDIT!
 
 package main
@@ -88,6 +89,26 @@ func _000InitialUpSql() (*asset, error) {
 	return a, nil
 }
 
+var __001SelectorIndexesUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\x73\x0e\x72\x75\x0c\x71\x55\xf0\xf4\x73\x71\x8d\x50\xc8\x4c\xa9\x88\x2f\x4e\xcd\x49\x4d\x2e\xc9\x2f\x2a\x8e\x2f\xa9\x2c\x48\x8d\x2f\x4b\xcc\x29\x4d\xe5\x52\x50\xf0\xf7\x53\x80\xcb\x68\x80\x64\x74\x14\xc0\x52\x9a\xd6\x5c\x5c\xce\xe8\x66\x14\xa5\xa6\x67\x16\x97\xa4\x16\xa5\xa6\xc4\xa7\xe6\x95\x14\x65\xa6\x16\xc7\x17\x24\x16\x01\x99\xf1\x99\x29\x10\xb3\x30\x55\x68\xc0\x55\x68\x02\x95\x84\x7b\xb8\x06\xb9\x2a\xa4\x00\x6d\x2c\x01\x2a\x49\x2c\x51\xf0\x0c\x56\xf0\x0b\xf5\xf1\xb1\xe6\x02\x00\x2c\xbe\xf3\x1f\xb1\x00\x00\x00")
+
+func _001SelectorIndexesUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__001SelectorIndexesUpSql,
+		"001-selector-indexes.up.sql",
+	)
+}
+
+func _001SelectorIndexesUpSql() (*asset, error) {
+	bytes, err := _001SelectorIndexesUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "001-selector-indexes.up.sql", size: 177, mode: os.FileMode(436), modTime: time.Unix(1792148084, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -140,7 +161,8 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
-	"000-initial.up.sql": _000InitialUpSql,
+	"000-initial.up.sql":          _000InitialUpSql,
+	"001-selector-indexes.up.sql": _001SelectorIndexesUpSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -184,7 +206,8 @@ type bintree struct {
 }
 
 var _bintree = &bintree{nil, map[string]*bintree{
-	"000-initial.up.sql": &bintree{_000InitialUpSql, map[string]*bintree{}},
+	"000-initial.up.sql":          &bintree{_000InitialUpSql, map[string]*bintree{}},
+	"001-selector-indexes.up.sql": &bintree{_001SelectorIndexesUpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory
@@ -3,8 +3,12 @@ package main
 //go:generate go-bindata -pkg $GOPACKAGE -o migrations.go -prefix _migrations/ _migrations/
 
 import (
-	"github.com/jinzhu/gorm"
+	"database/sql"
+	"fmt"
+	"sort"
 	"time"
+
+	"github.com/jinzhu/gorm"
 )
 
 type federatedBundle struct {
@@ -53,16 +57,85 @@ type selector struct {
 	RegisteredEntry   registeredEntry
 }
 
+// Migrations follow an expand/contract pattern: a migration may only add
+// tables, columns or indexes that the previous release ignores, and anything
+// the previous release still reads is removed in a later release. This keeps
+// a database migrated by release N usable by release N-1, so servers one
+// version apart can share it during a rolling upgrade.
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS migrations (
+  version    INTEGER   NOT NULL PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL
+);`
+
+// migrateDB applies the pending migrations in a single transaction. The
+// version is read after taking the write lock, so that servers starting
+// together on a shared database file do not both apply the same migrations.
 func migrateDB(db *gorm.DB) error {
-	for _, name := range AssetNames() {
-		migration, err := Asset(name)
+	tx, err := db.DB().Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := migrateTx(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func migrateTx(tx *sql.Tx) error {
+	if _, err := tx.Exec(createMigrationsTable); err != nil {
+		return err
+	}
+
+	// Transactions start deferred, take the write lock before reading. This
+	// statement changes nothing, it waits for other writers to finish.
+	if _, err := tx.Exec("DELETE FROM migrations WHERE version < 0"); err != nil {
+		return err
+	}
+
+	var dbVersion int
+	row := tx.QueryRow("SELECT COALESCE(MAX(version), 0) FROM migrations")
+	if err := row.Scan(&dbVersion); err != nil {
+		return err
+	}
+
+	// Asset names are prefixed with a sequence number, so sorting
+	// them yields the order in which they must be applied
+	names := AssetNames()
+	sort.Strings(names)
+
+	if err := checkSchemaCompatibility(dbVersion, len(names)); err != nil {
+		return err
+	}
+
+	for version := dbVersion; version < len(names); version++ {
+		migration, err := Asset(names[version])
 		if err != nil {
 			return err
 		}
-		if _, err := db.DB().Exec(string(migration)); err != nil {
+
+		if _, err := tx.Exec(string(migration)); err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO migrations (version, created_at) VALUES (?, ?)", version+1, time.Now())
+		if err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// checkSchemaCompatibility is a preflight check which ensures that the schema
+// found in the database can be used by this code. A schema at most one version
+// ahead is accepted, since expand-only migrations keep it readable by the
+// previous release.
+func checkSchemaCompatibility(dbVersion, codeVersion int) error {
+	if dbVersion > codeVersion+1 {
+		return fmt.Errorf("Database schema version %d is not compatible with this server, which supports up to version %d", dbVersion, codeVersion+1)
+	}
+
+	return nil
+}
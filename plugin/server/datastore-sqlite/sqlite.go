@@ -1,12 +1,21 @@
 package main
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/hcl"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/mattn/go-sqlite3"
 	"github.com/satori/go.uuid"
 
 	"github.com/spiffe/spire/proto/common"
@@ -24,12 +33,101 @@ var (
 	}
 )
 
+type configuration struct {
+	// SQL statements and datastore operations taking less than this are
+	// not logged
+	SlowQueryThreshold string `hcl:"slow_query_threshold" json:"slow_query_threshold"`
+
+	// Log one in this many of the SQL statements under the threshold,
+	// zero to log none of them
+	SQLLogSampling int `hcl:"sql_log_sampling" json:"sql_log_sampling"`
+
+	// Database file to use instead of an in-memory database, and
+	// its tuning knobs
+	FileName    string `hcl:"file_name" json:"file_name"`
+	JournalMode string `hcl:"journal_mode" json:"journal_mode"`
+	BusyTimeout string `hcl:"busy_timeout" json:"busy_timeout"`
+
+	// Number of WAL pages after which a commit checkpoints the WAL file
+	// into the database, zero to never checkpoint automatically
+	WALAutocheckpoint *int `hcl:"wal_autocheckpoint" json:"wal_autocheckpoint"`
+}
+
+// Only these journal modes are persisted in the database file. The others
+// apply to a single connection, and the pool may open more than one.
+var journalModes = map[string]bool{
+	"DELETE": true,
+	"WAL":    true,
+}
+
 type sqlitePlugin struct {
-	db *gorm.DB
+	db     *gorm.DB
+	logger *slowQueryLogger
+}
+
+// slowQueryLogger is a gorm logger which drops SQL statements that
+// completed in less than the threshold, except for one in every sampling of
+// them when sampling is set. Other messages are passed through. It also logs
+// how long each datastore operation took, under the same threshold.
+type slowQueryLogger struct {
+	// Number of fast statements seen so far. Kept first for 64-bit
+	// alignment, as it is updated atomically.
+	fast uint64
+
+	threshold time.Duration
+	sampling  uint64
+	logger    gorm.Logger
+}
+
+func newSlowQueryLogger(threshold time.Duration, sampling int) *slowQueryLogger {
+	return &slowQueryLogger{
+		threshold: threshold,
+		sampling:  uint64(sampling),
+		logger:    gorm.Logger{LogWriter: log.New(os.Stdout, "\r\n", 0)},
+	}
+}
+
+func (l *slowQueryLogger) Print(values ...interface{}) {
+	// SQL statements are logged as ("sql", source, duration, query, vars...)
+	if len(values) > 2 && values[0] == "sql" {
+		if duration, ok := values[2].(time.Duration); ok && duration < l.threshold && !l.sample() {
+			return
+		}
+	}
+
+	l.logger.Print(values...)
+}
+
+// sample reports whether a statement under the threshold should be logged
+// anyway. The first of every sampling statements is.
+func (l *slowQueryLogger) sample() bool {
+	if l.sampling == 0 {
+		return false
+	}
+
+	return (atomic.AddUint64(&l.fast, 1)-1)%l.sampling == 0
+}
+
+// operation logs the duration of a datastore operation, unless it completed
+// in less than the threshold. The fields are tagged so that log pipelines
+// can aggregate latencies per operation.
+func (l *slowQueryLogger) operation(name string, duration time.Duration) {
+	if duration < l.threshold {
+		return
+	}
+
+	l.logger.LogWriter.Println(fmt.Sprintf("[datastore] operation=%s duration=%s", name, duration))
+}
+
+// timeOperation logs how long the named operation has taken. It is
+// deferred at the start of every DataStore method.
+func (ds *sqlitePlugin) timeOperation(name string, start time.Time) {
+	ds.logger.operation(name, time.Since(start))
 }
 
 func (ds *sqlitePlugin) CreateFederatedEntry(
 	req *datastore.CreateFederatedEntryRequest) (*datastore.CreateFederatedEntryResponse, error) {
+	defer ds.timeOperation("CreateFederatedEntry", time.Now())
 
 	bundle := req.FederatedBundle
 	if bundle == nil {
@@ -51,6 +149,8 @@ func (ds *sqlitePlugin) CreateFederatedEntry(
 
 func (ds *sqlitePlugin) ListFederatedEntry(
 	*datastore.ListFederatedEntryRequest) (*datastore.ListFederatedEntryResponse, error) {
+	defer ds.timeOperation("ListFederatedEntry", time.Now())
+
 	var entries []federatedBundle
 	var response datastore.ListFederatedEntryResponse
 
@@ -67,6 +167,8 @@ func (ds *sqlitePlugin) ListFederatedEntry(
 
 func (ds *sqlitePlugin) UpdateFederatedEntry(
 	req *datastore.UpdateFederatedEntryRequest) (*datastore.UpdateFederatedEntryResponse, error) {
+	defer ds.timeOperation("UpdateFederatedEntry", time.Now())
+
 	bundle := req.FederatedBundle
 
 	if bundle == nil {
@@ -103,6 +205,8 @@ func (ds *sqlitePlugin) UpdateFederatedEntry(
 
 func (ds *sqlitePlugin) DeleteFederatedEntry(
 	req *datastore.DeleteFederatedEntryRequest) (*datastore.DeleteFederatedEntryResponse, error) {
+	defer ds.timeOperation("DeleteFederatedEntry", time.Now())
+
 	db := ds.db.Begin()
 
 	var model federatedBundle
@@ -128,6 +232,8 @@ func (ds *sqlitePlugin) DeleteFederatedEntry(
 
 func (ds *sqlitePlugin) CreateAttestedNodeEntry(
 	req *datastore.CreateAttestedNodeEntryRequest) (*datastore.CreateAttestedNodeEntryResponse, error) {
+	defer ds.timeOperation("CreateAttestedNodeEntry", time.Now())
+
 	entry := req.AttestedNodeEntry
 	if entry == nil {
 		return nil, errors.New("invalid request: missing attested node")
@@ -161,6 +267,8 @@ func (ds *sqlitePlugin) CreateAttestedNodeEntry(
 
 func (ds *sqlitePlugin) FetchAttestedNodeEntry(
 	req *datastore.FetchAttestedNodeEntryRequest) (*datastore.FetchAttestedNodeEntryResponse, error) {
+	defer ds.timeOperation("FetchAttestedNodeEntry", time.Now())
+
 	var model attestedNodeEntry
 	err := ds.db.Find(&model, "spiffe_id = ?", req.BaseSpiffeId).Error
 	switch {
@@ -181,6 +289,7 @@ func (ds *sqlitePlugin) FetchAttestedNodeEntry(
 
 func (ds *sqlitePlugin) FetchStaleNodeEntries(
 	*datastore.FetchStaleNodeEntriesRequest) (*datastore.FetchStaleNodeEntriesResponse, error) {
+	defer ds.timeOperation("FetchStaleNodeEntries", time.Now())
 
 	var models []attestedNodeEntry
 	if err := ds.db.Find(&models, "expires_at < ?", time.Now()).Error; err != nil {
@@ -204,6 +313,7 @@ func (ds *sqlitePlugin) FetchStaleNodeEntries(
 
 func (ds *sqlitePlugin) UpdateAttestedNodeEntry(
 	req *datastore.UpdateAttestedNodeEntryRequest) (*datastore.UpdateAttestedNodeEntryResponse, error) {
+	defer ds.timeOperation("UpdateAttestedNodeEntry", time.Now())
 
 	var model attestedNodeEntry
 
@@ -241,6 +351,8 @@ func (ds *sqlitePlugin) UpdateAttestedNodeEntry(
 
 func (ds *sqlitePlugin) DeleteAttestedNodeEntry(
 	req *datastore.DeleteAttestedNodeEntryRequest) (*datastore.DeleteAttestedNodeEntryResponse, error) {
+	defer ds.timeOperation("DeleteAttestedNodeEntry", time.Now())
+
 	db := ds.db.Begin()
 
 	var model attestedNodeEntry
@@ -250,7 +362,14 @@ func (ds *sqlitePlugin) DeleteAttestedNodeEntry(
 		return nil, err
 	}
 
-	if err := db.Delete(&model).Error; err != nil {
+	// Rows are removed rather than soft deleted, along with the selectors
+	// resolved for the node, so that deleting nodes frees the space
+	if err := db.Unscoped().Delete(&model).Error; err != nil {
+		db.Rollback()
+		return nil, err
+	}
+
+	if err := db.Unscoped().Where("spiffe_id = ?", model.SpiffeId).Delete(&nodeResolverMapEntry{}).Error; err != nil {
 		db.Rollback()
 		return nil, err
 	}
@@ -267,6 +386,7 @@ func (ds *sqlitePlugin) DeleteAttestedNodeEntry(
 
 func (ds *sqlitePlugin) CreateNodeResolverMapEntry(
 	req *datastore.CreateNodeResolverMapEntryRequest) (*datastore.CreateNodeResolverMapEntryResponse, error) {
+	defer ds.timeOperation("CreateNodeResolverMapEntry", time.Now())
 
 	entry := req.NodeResolverMapEntry
 	if entry == nil {
@@ -301,6 +421,8 @@ func (ds *sqlitePlugin) CreateNodeResolverMapEntry(
 
 func (ds *sqlitePlugin) FetchNodeResolverMapEntry(
 	req *datastore.FetchNodeResolverMapEntryRequest) (*datastore.FetchNodeResolverMapEntryResponse, error) {
+	defer ds.timeOperation("FetchNodeResolverMapEntry", time.Now())
+
 	var models []nodeResolverMapEntry
 
 	if err := ds.db.Find(&models, "spiffe_id = ?", req.BaseSpiffeId).Error; err != nil {
@@ -325,6 +447,7 @@ func (ds *sqlitePlugin) FetchNodeResolverMapEntry(
 
 func (ds *sqlitePlugin) DeleteNodeResolverMapEntry(
 	req *datastore.DeleteNodeResolverMapEntryRequest) (*datastore.DeleteNodeResolverMapEntryResponse, error) {
+	defer ds.timeOperation("DeleteNodeResolverMapEntry", time.Now())
 
 	entry := req.NodeResolverMapEntry
 	if entry == nil {
@@ -378,6 +501,7 @@ func (sqlitePlugin) RectifyNodeResolverMapEntries(
 
 func (ds *sqlitePlugin) CreateRegistrationEntry(
 	request *datastore.CreateRegistrationEntryRequest) (*datastore.CreateRegistrationEntryResponse, error) {
+	defer ds.timeOperation("CreateRegistrationEntry", time.Now())
 
 	// TODO: Validations should be done in the ProtoBuf level [https://github.com/spiffe/spire/issues/44]
 	if request.RegisteredEntry == nil {
@@ -423,6 +547,7 @@ func (ds *sqlitePlugin) CreateRegistrationEntry(
 
 func (ds *sqlitePlugin) FetchRegistrationEntry(
 	request *datastore.FetchRegistrationEntryRequest) (*datastore.FetchRegistrationEntryResponse, error) {
+	defer ds.timeOperation("FetchRegistrationEntry", time.Now())
 
 	var fetchedRegisteredEntry registeredEntry
 	err := ds.db.Find(&fetchedRegisteredEntry, "registered_entry_id = ?", request.RegisteredEntryId).Error
@@ -467,6 +592,8 @@ func (sqlitePlugin) DeleteRegistrationEntry(
 
 func (ds *sqlitePlugin) ListParentIDEntries(
 	request *datastore.ListParentIDEntriesRequest) (response *datastore.ListParentIDEntriesResponse, err error) {
+	defer ds.timeOperation("ListParentIDEntries", time.Now())
+
 	var fetchedRegisteredEntries []registeredEntry
 	err = ds.db.Find(&fetchedRegisteredEntries, "parent_id = ?", request.ParentId).Error
 
@@ -486,6 +613,7 @@ func (ds *sqlitePlugin) ListParentIDEntries(
 
 func (ds *sqlitePlugin) ListSelectorEntries(
 	request *datastore.ListSelectorEntriesRequest) (*datastore.ListSelectorEntriesResponse, error) {
+	defer ds.timeOperation("ListSelectorEntries", time.Now())
 
 	if len(request.Selectors) < 1 {
 		return &datastore.ListSelectorEntriesResponse{}, nil
@@ -520,8 +648,55 @@ func (sqlitePlugin) ListSpiffeEntries(
 	return &datastore.ListSpiffeEntriesResponse{}, errors.New("Not Implemented")
 }
 
-func (sqlitePlugin) Configure(*spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
-	return &spi.ConfigureResponse{}, nil
+func (ds *sqlitePlugin) Configure(req *spi.ConfigureRequest) (*spi.ConfigureResponse, error) {
+	resp := &spi.ConfigureResponse{}
+
+	// Parse HCL config payload into config struct
+	config := &configuration{}
+	hclTree, err := hcl.Parse(req.Configuration)
+	if err != nil {
+		resp.ErrorList = []string{err.Error()}
+		return resp, err
+	}
+	err = hcl.DecodeObject(&config, hclTree)
+	if err != nil {
+		resp.ErrorList = []string{err.Error()}
+		return resp, err
+	}
+
+	// Everything is validated before the database is swapped, so that an
+	// invalid configuration leaves the datastore as it was
+	var threshold time.Duration
+	if config.SlowQueryThreshold != "" {
+		threshold, err = time.ParseDuration(config.SlowQueryThreshold)
+		if err != nil {
+			err = fmt.Errorf("Unable to parse slow_query_threshold: %s", err)
+			resp.ErrorList = []string{err.Error()}
+			return resp, err
+		}
+	}
+
+	if config.SQLLogSampling < 0 {
+		err = fmt.Errorf("Invalid sql_log_sampling %d: must not be negative", config.SQLLogSampling)
+		resp.ErrorList = []string{err.Error()}
+		return resp, err
+	}
+
+	if config.FileName != "" {
+		db, err := openFileDB(config)
+		if err != nil {
+			resp.ErrorList = []string{err.Error()}
+			return resp, err
+		}
+
+		ds.db.Close()
+		ds.db = db
+	}
+
+	ds.logger = newSlowQueryLogger(threshold, config.SQLLogSampling)
+	ds.db.SetLogger(ds.logger)
+
+	return resp, nil
 }
 
 func (sqlitePlugin) GetPluginInfo(*spi.GetPluginInfoRequest) (*spi.GetPluginInfoResponse, error) {
@@ -578,20 +753,119 @@ func (ds *sqlitePlugin) convertEntries(fetchedRegisteredEntries []registeredEntr
 	return responseEntries, nil
 }
 
-func New() (datastore.DataStore, error) {
-	db, err := gorm.Open("sqlite3", ":memory:")
+// openFileDB opens the database file named in the config, applies the
+// configured tuning and migrates it. The configuration is validated before
+// the file is opened. The journal mode is persisted in the file, so it is
+// seen by every connection and by other servers sharing the file. The busy
+// timeout and the WAL autocheckpoint apply to a single connection, so they
+// are set on every connection the pool opens.
+func openFileDB(config *configuration) (*gorm.DB, error) {
+	dsn := config.FileName
+
+	if config.BusyTimeout != "" {
+		timeout, err := time.ParseDuration(config.BusyTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse busy_timeout: %s", err)
+		}
+		dsn = fmt.Sprintf("%s?_busy_timeout=%d", dsn, timeout/time.Millisecond)
+	}
+
+	journalMode := strings.ToUpper(config.JournalMode)
+	if journalMode != "" && !journalModes[journalMode] {
+		return nil, fmt.Errorf("Invalid journal_mode: %s", config.JournalMode)
+	}
+
+	driverName := "sqlite3"
+	if config.WALAutocheckpoint != nil {
+		if *config.WALAutocheckpoint < 0 {
+			return nil, fmt.Errorf("Invalid wal_autocheckpoint: %d", *config.WALAutocheckpoint)
+		}
+		driverName = walAutocheckpointDriver(*config.WALAutocheckpoint)
+	}
+
+	sqlDB, err := sql.Open(driverName, dsn)
 	if err != nil {
 		return nil, err
 	}
 
+	db, err := gorm.Open("sqlite3", sqlDB)
+	if err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
 	db.LogMode(true)
 
+	if journalMode != "" {
+		if err := db.Exec("PRAGMA journal_mode = " + journalMode).Error; err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
 	if err := migrateDB(db); err != nil {
+		db.Close()
 		return nil, err
 	}
 
+	return db, nil
+}
+
+var (
+	walDriversMtx sync.Mutex
+	walDrivers    = make(map[int]string)
+)
+
+// walAutocheckpointDriver returns the name of a sqlite3 driver which sets
+// the given wal_autocheckpoint on every connection it opens. Drivers cannot
+// be unregistered, so one is registered per value and then reused.
+func walAutocheckpointDriver(pages int) string {
+	walDriversMtx.Lock()
+	defer walDriversMtx.Unlock()
+
+	if name, ok := walDrivers[pages]; ok {
+		return name
+	}
+
+	name := fmt.Sprintf("sqlite3_wal_autocheckpoint_%d", pages)
+	sql.Register(name, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			_, err := conn.Exec(fmt.Sprintf("PRAGMA wal_autocheckpoint = %d", pages), nil)
+			return err
+		},
+	})
+	walDrivers[pages] = name
+
+	return name
+}
+
+func openDB(dsn string) (*gorm.DB, error) {
+	db, err := gorm.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db.LogMode(true)
+
+	return db, nil
+}
+
+func New() (datastore.DataStore, error) {
+	db, err := openDB(":memory:")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := migrateDB(db); err != nil {
+		return nil, err
+	}
+
+	// Log everything until a threshold is configured
+	logger := newSlowQueryLogger(0, 0)
+	db.SetLogger(logger)
+
 	return &sqlitePlugin{
-		db: db,
+		db:     db,
+		logger: logger,
 	}, nil
 }
 
@@ -2,10 +2,16 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"os"
+	"path"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -194,6 +200,37 @@ func Test_DeleteAttestedNodeEntry(t *testing.T) {
 	assert.Nil(t, fresp.AttestedNodeEntry)
 }
 
+func Test_DeleteAttestedNodeEntry_hardDelete(t *testing.T) {
+	ds := createDefault(t)
+
+	entry := &datastore.AttestedNodeEntry{
+		BaseSpiffeId:       "main",
+		AttestedDataType:   "aws-tag",
+		CertSerialNumber:   "badcafe",
+		CertExpirationDate: time.Now().Add(time.Hour).Format(datastore.TimeFormat),
+	}
+
+	_, err := ds.CreateAttestedNodeEntry(&datastore.CreateAttestedNodeEntryRequest{AttestedNodeEntry: entry})
+	require.NoError(t, err)
+
+	// Selectors of the node, and of another node which must be kept
+	createNodeResolverMapEntries(t, ds)
+
+	_, err = ds.DeleteAttestedNodeEntry(&datastore.DeleteAttestedNodeEntryRequest{BaseSpiffeId: entry.BaseSpiffeId})
+	require.NoError(t, err)
+
+	db := ds.(*sqlitePlugin).db
+
+	var nodes int
+	require.NoError(t, db.Unscoped().Model(&attestedNodeEntry{}).Count(&nodes).Error)
+	assert.Equal(t, 0, nodes)
+
+	var selectors []nodeResolverMapEntry
+	require.NoError(t, db.Unscoped().Find(&selectors).Error)
+	require.Len(t, selectors, 1)
+	assert.Equal(t, "other", selectors[0].SpiffeId)
+}
+
 func Test_CreateNodeResolverMapEntry(t *testing.T) {
 	ds := createDefault(t)
 
@@ -596,7 +633,260 @@ func Test_ListSpiffeEntriesEntry(t *testing.T) {
 }
 
 func Test_Configure(t *testing.T) {
-	t.Skipf("TODO")
+	ds := createDefault(t)
+
+	resp, err := ds.Configure(&spi.ConfigureRequest{Configuration: `slow_query_threshold = "100ms"`})
+	require.NoError(t, err)
+	assert.Empty(t, resp.ErrorList)
+
+	resp, err = ds.Configure(&spi.ConfigureRequest{Configuration: `slow_query_threshold = "foo"`})
+	assert.Error(t, err)
+	assert.NotEmpty(t, resp.ErrorList)
+}
+
+func Test_ConfigureFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spire-datastore-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := fmt.Sprintf(`
+		file_name = "%s"
+		journal_mode = "wal"
+		busy_timeout = "5s"`, path.Join(dir, "datastore.sqlite3"))
+
+	ds := createDefault(t)
+	_, err = ds.Configure(&spi.ConfigureRequest{Configuration: config})
+	require.NoError(t, err)
+
+	var journalMode string
+	err = ds.(*sqlitePlugin).db.Raw("PRAGMA journal_mode").Row().Scan(&journalMode)
+	require.NoError(t, err)
+	assert.Equal(t, "wal", journalMode)
+
+	entry := &datastore.AttestedNodeEntry{
+		BaseSpiffeId:       "foo",
+		AttestedDataType:   "aws-tag",
+		CertSerialNumber:   "badcafe",
+		CertExpirationDate: time.Now().Add(time.Hour).Format(datastore.TimeFormat),
+	}
+	_, err = ds.CreateAttestedNodeEntry(&datastore.CreateAttestedNodeEntryRequest{AttestedNodeEntry: entry})
+	require.NoError(t, err)
+
+	// A second plugin sees the same data, and migrating again is a no-op
+	ds2 := createDefault(t)
+	_, err = ds2.Configure(&spi.ConfigureRequest{Configuration: config})
+	require.NoError(t, err)
+
+	resp, err := ds2.FetchAttestedNodeEntry(&datastore.FetchAttestedNodeEntryRequest{BaseSpiffeId: "foo"})
+	require.NoError(t, err)
+	require.NotNil(t, resp.AttestedNodeEntry)
+	assert.Equal(t, "badcafe", resp.AttestedNodeEntry.CertSerialNumber)
+}
+
+func Test_ConfigureFileInvalid(t *testing.T) {
+	ds := createDefault(t)
+
+	_, err := ds.Configure(&spi.ConfigureRequest{Configuration: `
+		file_name = "datastore.sqlite3"
+		journal_mode = "foo"`})
+	assert.Error(t, err)
+
+	// Per-connection journal modes would not apply to the whole pool
+	_, err = ds.Configure(&spi.ConfigureRequest{Configuration: `
+		file_name = "datastore.sqlite3"
+		journal_mode = "memory"`})
+	assert.Error(t, err)
+
+	_, err = ds.Configure(&spi.ConfigureRequest{Configuration: `
+		file_name = "datastore.sqlite3"
+		busy_timeout = "foo"`})
+	assert.Error(t, err)
+}
+
+func Test_ConfigureInvalidKeepsDatabase(t *testing.T) {
+	ds := createDefault(t)
+	db := ds.(*sqlitePlugin).db
+
+	// The file is valid, the threshold is not. Nothing is applied.
+	_, err := ds.Configure(&spi.ConfigureRequest{Configuration: `
+		file_name = "datastore.sqlite3"
+		slow_query_threshold = "foo"`})
+	assert.Error(t, err)
+	assert.True(t, db == ds.(*sqlitePlugin).db)
+	assert.NoError(t, db.DB().Ping())
+}
+
+func Test_ConfigureFileWALAutocheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spire-datastore-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := fmt.Sprintf(`
+		file_name = "%s"
+		journal_mode = "wal"
+		wal_autocheckpoint = 50`, path.Join(dir, "datastore.sqlite3"))
+
+	ds := createDefault(t)
+	_, err = ds.Configure(&spi.ConfigureRequest{Configuration: config})
+	require.NoError(t, err)
+
+	db := ds.(*sqlitePlugin).db.DB()
+
+	// The pragma applies to a single connection. Hold one in a transaction
+	// so that the query below runs on another.
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	var pages int
+	require.NoError(t, tx.QueryRow("PRAGMA wal_autocheckpoint").Scan(&pages))
+	assert.Equal(t, 50, pages)
+	require.NoError(t, db.QueryRow("PRAGMA wal_autocheckpoint").Scan(&pages))
+	assert.Equal(t, 50, pages)
+
+	_, err = ds.Configure(&spi.ConfigureRequest{Configuration: `
+		file_name = "datastore.sqlite3"
+		wal_autocheckpoint = -1`})
+	assert.Error(t, err)
+}
+
+func Test_ConfigureFileConcurrentServers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spire-datastore-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := fmt.Sprintf(`
+		file_name = "%s"
+		journal_mode = "wal"
+		busy_timeout = "10s"`, path.Join(dir, "datastore.sqlite3"))
+
+	// Two servers sharing one file write at the same time. Writers are
+	// serialized by SQLite, the busy timeout makes them wait their turn
+	// instead of failing with "database is locked".
+	const servers, entriesPerServer = 2, 20
+	var stores []datastore.DataStore
+	for i := 0; i < servers; i++ {
+		ds := createDefault(t)
+		_, err = ds.Configure(&spi.ConfigureRequest{Configuration: config})
+		require.NoError(t, err)
+		stores = append(stores, ds)
+	}
+
+	errCh := make(chan error, servers*entriesPerServer)
+	var wg sync.WaitGroup
+	for i, ds := range stores {
+		wg.Add(1)
+		go func(i int, ds datastore.DataStore) {
+			defer wg.Done()
+			for j := 0; j < entriesPerServer; j++ {
+				entry := &datastore.AttestedNodeEntry{
+					BaseSpiffeId:       fmt.Sprintf("spiffe://example.org/node/%d-%d", i, j),
+					AttestedDataType:   "join_token",
+					CertSerialNumber:   fmt.Sprintf("%d-%d", i, j),
+					CertExpirationDate: time.Now().Add(time.Hour).Format(datastore.TimeFormat),
+				}
+				_, err := ds.CreateAttestedNodeEntry(&datastore.CreateAttestedNodeEntryRequest{AttestedNodeEntry: entry})
+				errCh <- err
+			}
+		}(i, ds)
+	}
+	wg.Wait()
+	close(errCh)
+
+	for err := range errCh {
+		require.NoError(t, err)
+	}
+
+	// Every server sees the writes of the others
+	for _, ds := range stores {
+		for i := 0; i < servers; i++ {
+			for j := 0; j < entriesPerServer; j++ {
+				resp, err := ds.FetchAttestedNodeEntry(&datastore.FetchAttestedNodeEntryRequest{
+					BaseSpiffeId: fmt.Sprintf("spiffe://example.org/node/%d-%d", i, j),
+				})
+				require.NoError(t, err)
+				require.NotNil(t, resp.AttestedNodeEntry)
+			}
+		}
+	}
+}
+
+type logRecorder struct {
+	lines [][]interface{}
+}
+
+func (r *logRecorder) Println(v ...interface{}) {
+	r.lines = append(r.lines, v)
+}
+
+func Test_slowQueryLogger(t *testing.T) {
+	recorder := &logRecorder{}
+	logger := slowQueryLogger{
+		threshold: 100 * time.Millisecond,
+		logger:    gorm.Logger{LogWriter: recorder},
+	}
+
+	logger.Print("sql", "sqlite.go:1", 10*time.Millisecond, "SELECT 1", []interface{}{})
+	assert.Empty(t, recorder.lines)
+
+	logger.Print("sql", "sqlite.go:1", time.Second, "SELECT 1", []interface{}{})
+	assert.Len(t, recorder.lines, 1)
+
+	logger.Print("log", "sqlite.go:1", errors.New("oops"))
+	assert.Len(t, recorder.lines, 2)
+}
+
+func Test_slowQueryLogger_sampling(t *testing.T) {
+	recorder := &logRecorder{}
+	logger := slowQueryLogger{
+		threshold: 100 * time.Millisecond,
+		sampling:  3,
+		logger:    gorm.Logger{LogWriter: recorder},
+	}
+
+	// One in three fast statements is logged, slow ones always are
+	for i := 0; i < 6; i++ {
+		logger.Print("sql", "sqlite.go:1", 10*time.Millisecond, "SELECT 1", []interface{}{})
+	}
+	assert.Len(t, recorder.lines, 2)
+
+	logger.Print("sql", "sqlite.go:1", time.Second, "SELECT 1", []interface{}{})
+	assert.Len(t, recorder.lines, 3)
+}
+
+func Test_ConfigureInvalidSQLLogSampling(t *testing.T) {
+	ds := createDefault(t)
+
+	resp, err := ds.Configure(&spi.ConfigureRequest{Configuration: `sql_log_sampling = -1`})
+	require.Error(t, err)
+	assert.Equal(t, []string{err.Error()}, resp.ErrorList)
+}
+
+func Test_slowQueryLogger_operation(t *testing.T) {
+	recorder := &logRecorder{}
+	logger := slowQueryLogger{
+		threshold: 100 * time.Millisecond,
+		logger:    gorm.Logger{LogWriter: recorder},
+	}
+
+	logger.operation("FetchAttestedNodeEntry", 10*time.Millisecond)
+	assert.Empty(t, recorder.lines)
+
+	logger.operation("FetchAttestedNodeEntry", time.Second)
+	require.Len(t, recorder.lines, 1)
+	assert.Equal(t, []interface{}{"[datastore] operation=FetchAttestedNodeEntry duration=1s"}, recorder.lines[0])
+}
+
+func Test_timeOperation(t *testing.T) {
+	recorder := &logRecorder{}
+	ds := createDefault(t).(*sqlitePlugin)
+	ds.logger = &slowQueryLogger{logger: gorm.Logger{LogWriter: recorder}}
+
+	_, err := ds.FetchAttestedNodeEntry(&datastore.FetchAttestedNodeEntryRequest{BaseSpiffeId: "foo"})
+	require.NoError(t, err)
+
+	require.Len(t, recorder.lines, 1)
+	assert.Contains(t, recorder.lines[0][0], "[datastore] operation=FetchAttestedNodeEntry duration=")
 }
 
 func Test_GetPluginInfo(t *testing.T) {
@@ -606,6 +896,62 @@ func Test_GetPluginInfo(t *testing.T) {
 	require.NotNil(t, resp)
 }
 
+func Test_migrateDB_idempotent(t *testing.T) {
+	ds := createDefault(t)
+
+	err := migrateDB(ds.(*sqlitePlugin).db)
+	require.NoError(t, err)
+}
+
+func Test_migrateDB_concurrentServers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spire-datastore-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Servers starting together on a new file must not both try to
+	// create the initial schema
+	for i := 0; i < 5; i++ {
+		config := fmt.Sprintf(`
+			file_name = "%s"
+			journal_mode = "wal"
+			busy_timeout = "10s"`, path.Join(dir, fmt.Sprintf("datastore-%d.sqlite3", i)))
+
+		const servers = 3
+		errCh := make(chan error, servers)
+		for j := 0; j < servers; j++ {
+			ds := createDefault(t)
+			go func() {
+				_, err := ds.Configure(&spi.ConfigureRequest{Configuration: config})
+				errCh <- err
+			}()
+		}
+
+		for j := 0; j < servers; j++ {
+			require.NoError(t, <-errCh)
+		}
+	}
+}
+
+func Test_migrateDB_selectorIndexes(t *testing.T) {
+	ds := createDefault(t)
+
+	for _, index := range []string{"idx_selectors_type_value", "idx_registered_entries_parent_id"} {
+		var count int
+		row := ds.(*sqlitePlugin).db.DB().QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = ?", index)
+		require.NoError(t, row.Scan(&count))
+		assert.Equal(t, 1, count, index)
+	}
+}
+
+func Test_checkSchemaCompatibility(t *testing.T) {
+	codeVersion := len(AssetNames())
+
+	assert.NoError(t, checkSchemaCompatibility(0, codeVersion))
+	assert.NoError(t, checkSchemaCompatibility(codeVersion, codeVersion))
+	assert.NoError(t, checkSchemaCompatibility(codeVersion+1, codeVersion))
+	assert.Error(t, checkSchemaCompatibility(codeVersion+2, codeVersion))
+}
+
 func Test_race(t *testing.T) {
 	ds := createDefault(t)
 
@@ -38,14 +38,16 @@ type configuration struct {
 	TrustDomain  string `hcl:"trust_domain" json:"trust_domain"`
 	CertFilePath string `hcl:"cert_file_path" json:"cert_file_path"`
 	KeyFilePath  string `hcl:"key_file_path" json:"key_file_path"`
+	Backdate     string `hcl:"backdate" json:"backdate"`
 }
 
 type memoryPlugin struct {
 	config *configuration
 
-	key    *ecdsa.PrivateKey
-	cert   *x509.Certificate
-	serial int64
+	key      *ecdsa.PrivateKey
+	cert     *x509.Certificate
+	serial   int64
+	backdate time.Duration
 
 	mtx *sync.RWMutex
 }
@@ -68,6 +70,12 @@ func (m *memoryPlugin) Configure(req *spi.ConfigureRequest) (*spi.ConfigureRespo
 		return resp, err
 	}
 
+	backdate, err := ParseBackdate(config.Backdate, config.TTL)
+	if err != nil {
+		resp.ErrorList = []string{err.Error()}
+		return resp, err
+	}
+
 	keyPEM, err := ioutil.ReadFile(config.KeyFilePath)
 	if err != nil {
 		return nil, fmt.Errorf("Could not read %s: %s", config.KeyFilePath, err)
@@ -113,8 +121,10 @@ func (m *memoryPlugin) Configure(req *spi.ConfigureRequest) (*spi.ConfigureRespo
 	m.config.TTL = config.TTL
 	m.config.KeyFilePath = config.KeyFilePath
 	m.config.CertFilePath = config.CertFilePath
+	m.config.Backdate = config.Backdate
 	m.cert = cert
 	m.key = key
+	m.backdate = backdate
 
 	log.Print("Plugin successfully configured")
 	return &spi.ConfigureResponse{}, nil
@@ -159,7 +169,7 @@ func (m *memoryPlugin) SubmitCSR(request *upstreamca.SubmitCSRRequest) (*upstrea
 		Subject:         csr.Subject,
 		Issuer:          m.cert.Subject,
 		SerialNumber:    big.NewInt(serial),
-		NotBefore:       now,
+		NotBefore:       now.Add(-m.backdate),
 		NotAfter:        now.Add(expiry),
 		KeyUsage: x509.KeyUsageDigitalSignature |
 			x509.KeyUsageCertSign |
@@ -183,6 +193,33 @@ func (m *memoryPlugin) SubmitCSR(request *upstreamca.SubmitCSRRequest) (*upstrea
 	}, nil
 }
 
+// ParseBackdate parses how far in the past to set the NotBefore of issued
+// certificates. An unset backdate is zero; otherwise it must be positive and
+// shorter than the TTL, or certificates would be born already expired.
+func ParseBackdate(backdate, ttl string) (time.Duration, error) {
+	if backdate == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(backdate)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to parse backdate: %s", err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("Backdate must not be negative: %s", backdate)
+	}
+
+	expiry, err := time.ParseDuration(ttl)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to parse TTL: %s", err)
+	}
+	if d >= expiry {
+		return 0, fmt.Errorf("Backdate %s must be shorter than the TTL %s", backdate, ttl)
+	}
+
+	return d, nil
+}
+
 func ParseSpiffeCsr(csrDER []byte, trustDomain string) (csr *x509.CertificateRequest, err error) {
 	csr, err = x509.ParseCertificateRequest(csrDER)
 	if err != nil {
@@ -1,11 +1,13 @@
 package pkg
 
 import (
+	"crypto/x509"
 	"encoding/pem"
 	"io/ioutil"
 	"path/filepath"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -76,6 +78,70 @@ func TestMemory_SubmitInvalidCSR(t *testing.T) {
 	}
 }
 
+func TestMemory_SubmitCSRBackdate(t *testing.T) {
+	m, err := NewWithDefault("_test_data/keys/private_key.pem", "_test_data/keys/cert.pem")
+	require.NoError(t, err)
+
+	_, err = m.Configure(&spi.ConfigureRequest{
+		Configuration: `{"trust_domain":"localhost", "ttl":"1h", "backdate":"1m", "key_file_path":"_test_data/keys/private_key.pem", "cert_file_path":"_test_data/keys/cert.pem"}`,
+	})
+	require.NoError(t, err)
+
+	csrPEM, err := ioutil.ReadFile("_test_data/csr_valid/csr_1.pem")
+	require.NoError(t, err)
+	block, _ := pem.Decode(csrPEM)
+
+	now := time.Now()
+	resp, err := m.SubmitCSR(&upstreamca.SubmitCSRRequest{Csr: block.Bytes})
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(resp.Cert)
+	require.NoError(t, err)
+	assert.True(t, cert.NotBefore.Before(now.Add(-59*time.Second)))
+	assert.True(t, cert.NotAfter.After(now.Add(59*time.Minute)))
+}
+
+func TestMemory_ConfigureInvalidBackdate(t *testing.T) {
+	for _, backdate := range []string{"abc", "-1m", "1h", "2h"} {
+		m, err := NewWithDefault("_test_data/keys/private_key.pem", "_test_data/keys/cert.pem")
+		require.NoError(t, err)
+
+		resp, err := m.Configure(&spi.ConfigureRequest{
+			Configuration: `{"trust_domain":"localhost", "ttl":"1h", "backdate":"` + backdate + `", "key_file_path":"_test_data/keys/private_key.pem", "cert_file_path":"_test_data/keys/cert.pem"}`,
+		})
+		assert.Error(t, err, backdate)
+		assert.Equal(t, []string{err.Error()}, resp.GetErrorList())
+	}
+}
+
+func TestParseBackdate(t *testing.T) {
+	var tests = []struct {
+		backdate string
+		ttl      string
+		expected time.Duration
+		err      string
+	}{
+		{"", "abc", 0, ""},
+		{"1m", "1h", time.Minute, ""},
+		{"abc", "1h", 0, "Unable to parse backdate"},
+		{"-1m", "1h", 0, "Backdate must not be negative: -1m"},
+		{"1m", "abc", 0, "Unable to parse TTL"},
+		{"1h", "1h", 0, "Backdate 1h must be shorter than the TTL 1h"},
+	}
+
+	for _, tt := range tests {
+		d, err := ParseBackdate(tt.backdate, tt.ttl)
+		if tt.err != "" {
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.err)
+			}
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, d)
+	}
+}
+
 func TestMemory_race(t *testing.T) {
 	m, err := NewWithDefault("_test_data/keys/private_key.pem", "_test_data/keys/cert.pem")
 	require.NoError(t, err)